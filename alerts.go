@@ -0,0 +1,51 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"errors"
+)
+
+// errAlertBudget halts the walk once max-alerts flagged directories have been reported, if requested.
+var errAlertBudget = errors.New("maximum number of alerts reported")
+
+var alertsReported int64
+
+// withinAlertBudget checks if a result may still be reported under max-alerts, accounting unreported ones.
+func withinAlertBudget(r Result) bool {
+	if *maxAlerts <= 0 || r.Kind == resultScanned || r.Kind == resultSkipped {
+		return true
+	}
+	if alertsReported < *maxAlerts {
+		alertsReported++
+		return true
+	}
+
+	summary.AlertsCapped = true
+	summary.Unreported++
+	return false
+}
+
+// alertBudgetExhausted checks if the walk should stop as max-alerts flagged directories have been reported.
+func alertBudgetExhausted() bool {
+	return *stopAtMaxAlertsFlag && *maxAlerts > 0 && alertsReported >= *maxAlerts
+}
@@ -0,0 +1,50 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestAlertBudget(t *testing.T) {
+	*maxAlerts, *stopAtMaxAlertsFlag = 2, true
+	defer func() {
+		*maxAlerts, *stopAtMaxAlertsFlag = 0, false
+		alertsReported, summary.AlertsCapped, summary.Unreported = 0, false, 0
+	}()
+
+	var reported int
+	for _, r := range []Result{
+		{Kind: resultLarge}, {Kind: resultScanned}, {Kind: resultFanout}, {Kind: resultSuspect}, {Kind: resultLarge},
+	} {
+		if withinAlertBudget(r) && r.Kind != resultScanned {
+			reported++
+		}
+	}
+	if reported != 2 || summary.Unreported != 2 || !summary.AlertsCapped {
+		t.Errorf("withinAlertBudget() reported %v with %v unreported (capped %v); want 2, 2 and true", reported,
+			summary.Unreported, summary.AlertsCapped)
+	}
+	if !alertBudgetExhausted() {
+		t.Errorf("alertBudgetExhausted() = false after %v alerts; want true", alertsReported)
+	}
+}
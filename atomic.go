@@ -0,0 +1,73 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// An atomicFile is a temporary file renamed into its final path only when committed, so that readers never see
+// a partially written file.
+type atomicFile struct {
+	*os.File
+	path string
+}
+
+// createAtomic creates a temporary file next to a given path.
+func createAtomic(path string) (*atomicFile, error) {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return nil, err
+	}
+	return &atomicFile{File: f, path: path}, nil
+}
+
+// Commit flushes and closes the temporary file, renaming it into place.
+func (a *atomicFile) Commit() error {
+	if err := a.Sync(); err != nil {
+		a.Abort()
+		return err
+	}
+	if err := a.Close(); err != nil {
+		_ = os.Remove(a.Name())
+		return err
+	}
+
+	// ioutil.TempFile() creates files readable only by the owner
+	if err := os.Chmod(a.Name(), 0644); err != nil {
+		_ = os.Remove(a.Name())
+		return err
+	}
+	if err := os.Rename(a.Name(), a.path); err != nil {
+		_ = os.Remove(a.Name())
+		return err
+	}
+	return nil
+}
+
+// Abort closes and removes the temporary file, leaving any previous file in place intact.
+func (a *atomicFile) Abort() {
+	_ = a.Close()
+	_ = os.Remove(a.Name())
+}
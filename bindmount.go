@@ -0,0 +1,139 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dkorunic/findlargedir/fsinfo"
+)
+
+// A bindKey identifies a directory by its device and location within the filesystem, regardless of mount point.
+type bindKey struct {
+	dev  uint64
+	path string
+}
+
+// A bindIndex detects directories reached more than once through bind mounts of the same filesystem.
+type bindIndex struct {
+	mounts map[uint64][]fsinfo.Info
+	seen   map[bindKey]string
+}
+
+// binds is nil unless bind mount duplicates are collapsed.
+var binds *bindIndex
+
+// loadBindIndex builds a bind mount index from mounted filesystems, or nil if none are mounted more than once.
+func loadBindIndex() *bindIndex {
+	mounts, err := fsinfo.Mounts()
+	if err != nil {
+		return nil
+	}
+	return newBindIndex(mounts)
+}
+
+// newBindIndex indexes devices mounted more than once, where locations within the filesystem are known.
+func newBindIndex(mounts []fsinfo.Info) *bindIndex {
+	byDev := make(map[uint64][]fsinfo.Info)
+	for _, m := range mounts {
+		if m.Root != "" {
+			byDev[m.Dev] = append(byDev[m.Dev], m)
+		}
+	}
+	for dev, ms := range byDev {
+		if len(ms) < 2 {
+			delete(byDev, dev)
+		}
+	}
+	if len(byDev) == 0 {
+		return nil
+	}
+	return &bindIndex{mounts: byDev, seen: make(map[bindKey]string)}
+}
+
+// duplicateOf returns the path a directory was first reached at, if it was already reached through another mount.
+// Only directories reachable through more than one mount are remembered.
+func (b *bindIndex) duplicateOf(dev uint64, path string) (string, bool) {
+	ms, ok := b.mounts[dev]
+	if !ok {
+		return "", false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+
+	// Longest mount point holding the path tells its location within the filesystem
+	var m *fsinfo.Info
+	for i := range ms {
+		if isUnder(abs, ms[i].Mountpoint) && (m == nil || len(ms[i].Mountpoint) > len(m.Mountpoint)) {
+			m = &ms[i]
+		}
+	}
+	if m == nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(m.Mountpoint, abs)
+	if err != nil {
+		return "", false
+	}
+	key := bindKey{dev: dev, path: filepath.Join(m.Root, rel)}
+
+	var reachable int
+	for i := range ms {
+		if isUnder(key.path, ms[i].Root) {
+			reachable++
+		}
+	}
+	if reachable < 2 {
+		return "", false
+	}
+
+	if first, ok := b.seen[key]; ok {
+		return first, true
+	}
+	b.seen[key] = path
+	return "", false
+}
+
+// isUnder checks if path is dir or below it.
+func isUnder(path, dir string) bool {
+	return path == dir || dir == "/" || strings.HasPrefix(path, dir+"/")
+}
+
+// reportBindDuplicate reports a directory skipped as a bind mount duplicate of an already scanned one.
+func reportBindDuplicate(path, first string, fi os.FileInfo) {
+	log.Printf("Directory %q is a bind mount duplicate of already scanned %q, skipping.", redacted(path), redacted(first))
+	summary.BindMounts++
+	if !*reportSkipsFlag {
+		return
+	}
+
+	r := Result{Path: path, Kind: resultSkipped, SkipReason: skipBindMount, DuplicateOf: first, Device: getDev(fi),
+		Tags: tags}
+	r.Label = deviceLabel(r.Device, path)
+	summary.addResult(r)
+	output.Result(r)
+}
@@ -0,0 +1,67 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/dkorunic/findlargedir/fsinfo"
+)
+
+func TestBindIndex(t *testing.T) {
+	mounts := []fsinfo.Info{
+		{Dev: 1, Mountpoint: "/", Root: "/"},
+		{Dev: 2, Mountpoint: "/data", Root: "/"},
+		{Dev: 2, Mountpoint: "/srv/export", Root: "/export"},
+		{Dev: 3, Mountpoint: "/scratch", Root: "/"},
+	}
+	b := newBindIndex(mounts)
+	if b == nil {
+		t.Fatal("newBindIndex() = nil; want an index of device mounted twice")
+	}
+
+	for _, tt := range []struct {
+		dev   uint64
+		path  string
+		first string
+		dup   bool
+	}{
+		{1, "/home", "", false},
+		{2, "/data/other", "", false},
+		{2, "/data/export", "", false},
+		{2, "/data/export/a", "", false},
+		{2, "/srv/export", "/data/export", true},
+		{2, "/srv/export/a", "/data/export/a", true},
+		{2, "/srv/export/b", "", false},
+		{2, "/data/export/b", "/srv/export/b", true},
+		{3, "/scratch/export", "", false},
+	} {
+		first, dup := b.duplicateOf(tt.dev, tt.path)
+		if first != tt.first || dup != tt.dup {
+			t.Errorf("duplicateOf(%v, %q) = %q, %v; want %q, %v", tt.dev, tt.path, first, dup, tt.first, tt.dup)
+		}
+	}
+
+	if b := newBindIndex(mounts[:2]); b != nil {
+		t.Errorf("newBindIndex() without repeated devices = %+v; want nil", b)
+	}
+}
@@ -0,0 +1,71 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// A sizeBucket counts directories with estimates of at least Min and below Max entries, unbounded if Max is zero.
+type sizeBucket struct {
+	Min   int64 `json:"min"`
+	Max   int64 `json:"max,omitempty"`
+	Count int64 `json:"count"`
+}
+
+// parseBuckets turns increasing bucket boundaries into buckets, including one below the first boundary.
+func parseBuckets(list []string) ([]sizeBucket, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	var buckets []sizeBucket
+	var prev int64
+	for _, v := range list {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= prev {
+			return nil, fmt.Errorf("invalid bucket boundary %q, expected increasing positive counts", v)
+		}
+		buckets = append(buckets, sizeBucket{Min: prev, Max: n})
+		prev = n
+	}
+	return append(buckets, sizeBucket{Min: prev}), nil
+}
+
+// addToBucket counts an estimate in its bucket.
+func addToBucket(buckets []sizeBucket, estimate int64) {
+	for i := range buckets {
+		if estimate >= buckets[i].Min && (buckets[i].Max == 0 || estimate < buckets[i].Max) {
+			buckets[i].Count++
+			return
+		}
+	}
+}
+
+// String describes bucket range, i.e. 10000-100000 or >=1000000.
+func (b sizeBucket) String() string {
+	if b.Max == 0 {
+		return fmt.Sprintf(">=%v", b.Min)
+	}
+	return fmt.Sprintf("%v-%v", b.Min, b.Max)
+}
@@ -0,0 +1,51 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuckets(t *testing.T) {
+	buckets, err := parseBuckets([]string{"10000", "100000", "1000000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int64{500, 10000, 99999, 250000, 5000000, 1000000} {
+		addToBucket(buckets, n)
+	}
+
+	want := []sizeBucket{{0, 10000, 1}, {10000, 100000, 2}, {100000, 1000000, 1}, {1000000, 0, 2}}
+	if !reflect.DeepEqual(buckets, want) {
+		t.Errorf("buckets = %v; want %v", buckets, want)
+	}
+	if s := buckets[1].String() + " " + buckets[3].String(); s != "10000-100000 >=1000000" {
+		t.Errorf("bucket descriptions = %q; want 10000-100000 >=1000000", s)
+	}
+
+	for _, list := range [][]string{{"100", "10"}, {"0"}, {"x"}} {
+		if _, err := parseBuckets(list); err == nil {
+			t.Errorf("parseBuckets(%q) succeeded; want error", list)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// A partialDevice is a filesystem that was only partially scanned as its time budget got exhausted.
+type partialDevice struct {
+	Device uint64 `json:"device"`
+	Label  string `json:"device_label"`
+	Path   string `json:"path"`
+}
+
+// An fsBudget accounts walk time spent on each filesystem, charging time between walker callbacks to the device of
+// the previous directory.
+type fsBudget struct {
+	max     time.Duration
+	spent   map[uint64]time.Duration
+	partial map[uint64]bool
+	lastDev uint64
+	last    time.Time
+}
+
+// budget holds per-filesystem walk time accounting of the whole run.
+var budget *fsBudget
+
+func newFSBudget(max time.Duration) *fsBudget {
+	return &fsBudget{max: max, spent: make(map[uint64]time.Duration), partial: make(map[uint64]bool)}
+}
+
+// reset stops charging time until the next directory, i.e. in between roots.
+func (b *fsBudget) reset() {
+	b.last = time.Time{}
+}
+
+// charge accounts time since previous directory and checks if a device has exhausted its budget, marking it as
+// partially scanned the first time.
+func (b *fsBudget) charge(dev uint64, path string, now time.Time) bool {
+	if !b.last.IsZero() {
+		b.spent[b.lastDev] += now.Sub(b.last)
+	}
+	b.lastDev, b.last = dev, now
+
+	if b.spent[dev] <= b.max {
+		return false
+	}
+	if !b.partial[dev] {
+		b.partial[dev] = true
+		p := partialDevice{Device: dev, Label: deviceLabel(dev, path), Path: path}
+		summary.Partial = append(summary.Partial, p)
+		log.Printf("Time budget of %v exceeded on %v filesystem at %q, skipping the rest of it.", b.max, redactedLabel(p.Label),
+			redacted(path))
+	}
+	return true
+}
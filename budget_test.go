@@ -0,0 +1,55 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFSBudget(t *testing.T) {
+	defer func(s Summary) { summary = s }(summary)
+	summary.Partial = nil
+
+	b := newFSBudget(time.Second)
+	now := time.Now()
+	if b.charge(1, "/a", now) || b.charge(2, "/b", now.Add(time.Millisecond*500)) {
+		t.Errorf("charge() within budget = true; want false")
+	}
+	// Device 2 is charged for 2s spent in between, device 1 only for 500ms
+	if b.charge(1, "/a/x", now.Add(time.Millisecond*2500)) || b.spent[2] != time.Second*2 {
+		t.Errorf("device 2 spent = %v; want 2s with device 1 within budget", b.spent[2])
+	}
+	if !b.charge(2, "/b/x", now.Add(time.Millisecond*2600)) {
+		t.Errorf("charge() on device over budget = false; want true")
+	}
+
+	// Time in between roots is not charged
+	b.reset()
+	if b.charge(1, "/a/y", now.Add(time.Hour)) {
+		t.Errorf("charge() after reset = true; want false")
+	}
+
+	if len(summary.Partial) != 1 || summary.Partial[0].Device != 2 || summary.Partial[0].Path != "/b/x" {
+		t.Errorf("summary partial devices = %+v; want device 2 only", summary.Partial)
+	}
+}
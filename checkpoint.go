@@ -0,0 +1,190 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultCheckpointInterval = time.Second * 30
+
+// A checkpointRoot records results of fully processed top-level directories of a single root, keyed by name.
+type checkpointRoot struct {
+	Completed map[string][]Result `json:"completed"`
+}
+
+// A checkpointState tracks scan progress at top-level directory granularity so interrupted scans can resume.
+type checkpointState struct {
+	Threshold        int64                      `json:"threshold"`
+	FSTypeThresholds map[string]int64           `json:"fstype_thresholds,omitempty"`
+	Roots            map[string]*checkpointRoot `json:"roots"`
+
+	mu        sync.Mutex
+	path      string
+	root      *checkpointRoot
+	current   []Result
+	lastWrite time.Time
+	done      bool
+}
+
+// checkpoint holds scan progress, nil when checkpointing is disabled.
+var checkpoint *checkpointState
+
+// loadCheckpoint reads a checkpoint file, starting afresh if it is missing, unreadable or made with other setting.
+func loadCheckpoint(path string) *checkpointState {
+	c := &checkpointState{Threshold: *alertThreshold, FSTypeThresholds: fsTypeThresholds,
+		Roots: make(map[string]*checkpointRoot), path: path, lastWrite: time.Now()}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			reportError(path, err)
+		}
+		return c
+	}
+
+	var saved checkpointState
+	if err := json.Unmarshal(b, &saved); err != nil || saved.Roots == nil {
+		log.Printf("Checkpoint %q is damaged, starting from scratch.", path)
+		return c
+	}
+	if saved.Threshold != *alertThreshold || !sameThresholds(saved.FSTypeThresholds, fsTypeThresholds) {
+		log.Printf("Checkpoint %q was made with threshold %v, starting from scratch.", path, saved.Threshold)
+		return c
+	}
+
+	c.Roots = saved.Roots
+	return c
+}
+
+// begin starts processing of a root, returning results of its already completed top-level directories.
+func (c *checkpointState) begin(rootPath string) map[string][]Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r, ok := c.Roots[rootPath]
+	if !ok || r.Completed == nil {
+		r = &checkpointRoot{Completed: make(map[string][]Result)}
+		c.Roots[rootPath] = r
+	}
+	c.root = r
+	c.current = nil
+	return r.Completed
+}
+
+// isCompleted checks if a top-level directory of current root has been fully processed.
+func (c *checkpointState) isCompleted(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.root.Completed[name]
+	return ok
+}
+
+// start begins processing of a top-level directory, dropping results of any unfinished one.
+func (c *checkpointState) start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.current = nil
+}
+
+// result records a result within a top-level directory being processed.
+func (c *checkpointState) result(r Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.current = append(c.current, r)
+}
+
+// complete marks a top-level directory as fully processed, writing out checkpoint periodically.
+func (c *checkpointState) complete(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	results := c.current
+	if results == nil {
+		results = []Result{}
+	}
+	c.root.Completed[name] = results
+	c.current = nil
+
+	if time.Since(c.lastWrite) >= defaultCheckpointInterval {
+		c.write()
+	}
+}
+
+// save writes out checkpoint, unless the whole scan is already done.
+func (c *checkpointState) save() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.done {
+		c.write()
+	}
+}
+
+// write atomically replaces checkpoint file, so that an interrupted write leaves previous checkpoint intact.
+func (c *checkpointState) write() {
+	c.lastWrite = time.Now()
+
+	f, err := createAtomic(c.path)
+	if err != nil {
+		reportError(c.path, err)
+		return
+	}
+	if err := json.NewEncoder(f).Encode(c); err != nil {
+		f.Abort()
+		reportError(c.path, err)
+		return
+	}
+	if err := f.Commit(); err != nil {
+		reportError(c.path, err)
+	}
+}
+
+// remove deletes checkpoint file once the whole scan is done.
+func (c *checkpointState) remove() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.done = true
+	if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+		reportError(c.path, err)
+	}
+}
+
+// sortedNames returns completed top-level directory names in order.
+func sortedNames(completed map[string][]Result) []string {
+	names := make([]string, 0, len(completed))
+	for name := range completed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
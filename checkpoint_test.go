@@ -0,0 +1,77 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scan.json")
+
+	c := loadCheckpoint(path)
+	c.begin("/srv")
+	c.start()
+	c.result(Result{Path: "/srv/a/big", Kind: resultLarge, Estimate: 100000})
+	c.complete("a")
+	c.start()
+	c.result(Result{Path: "/srv/b/big", Kind: resultLarge, Estimate: 200000})
+	c.save()
+
+	// Unfinished top-level directory b is not recorded
+	completed := loadCheckpoint(path).begin("/srv")
+	want := map[string][]Result{"a": {{Path: "/srv/a/big", Kind: resultLarge, Estimate: 100000}}}
+	if !reflect.DeepEqual(completed, want) {
+		t.Errorf("loaded checkpoint = %+v; want %+v", completed, want)
+	}
+
+	c.remove()
+	c.save()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("checkpoint %q exists after remove; want removed", path)
+	}
+}
+
+func TestCheckpointDamaged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scan.json")
+
+	if err := ioutil.WriteFile(path, []byte(`{"threshold":50000,"roots":{"/srv":{"comp`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if completed := loadCheckpoint(path).begin("/srv"); len(completed) != 0 {
+		t.Errorf("damaged checkpoint loaded %+v; want nothing", completed)
+	}
+}
@@ -0,0 +1,39 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestCleanupCommand(t *testing.T) {
+	for path, want := range map[string]string{
+		"/var/spool":         `find '/var/spool' -mindepth 1 -delete`,
+		"/tmp/it's here":     `find '/tmp/it'\''s here' -mindepth 1 -delete`,
+		"/tmp/$(rm -rf ~) *": `find '/tmp/$(rm -rf ~) *' -mindepth 1 -delete`,
+		"-rf":                `find './-rf' -mindepth 1 -delete`,
+	} {
+		if got := cleanupCommand(path); got != want {
+			t.Errorf("cleanupCommand(%q) = %q; want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// A compactingReporter buffers results of each root and passes them on with the longest common directory of their
+// paths, so that paths can be displayed relative to it.
+type compactingReporter struct {
+	reporter
+	results []Result
+}
+
+func (c *compactingReporter) Result(r Result) {
+	c.results = append(c.results, r)
+}
+
+func (c *compactingReporter) Flush() {
+	var paths []string
+	for _, r := range c.results {
+		paths = append(paths, r.Path)
+	}
+	prefix := commonDir(paths)
+
+	for _, r := range c.results {
+		r.CommonPrefix = prefix
+		c.reporter.Result(r)
+	}
+	c.results = nil
+	c.reporter.Flush()
+}
+
+// commonDir returns the longest directory holding all paths, or an empty string if there is no such directory
+// deeper than the filesystem root.
+func commonDir(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+
+	prefix := filepath.Dir(paths[0])
+	for _, p := range paths[1:] {
+		for !isUnder(filepath.Dir(p), prefix) && prefix != filepath.Dir(prefix) {
+			prefix = filepath.Dir(prefix)
+		}
+	}
+	if prefix == filepath.Dir(prefix) {
+		return ""
+	}
+	return prefix
+}
+
+// relativePath returns path relative to a common prefix, if it is under it.
+func relativePath(path, prefix string) string {
+	if prefix == "" || !isUnder(path, prefix) {
+		return path
+	}
+	return strings.TrimPrefix(path[len(prefix):], "/")
+}
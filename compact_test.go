@@ -0,0 +1,65 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCommonDir(t *testing.T) {
+	for _, tt := range []struct {
+		paths []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"/srv/data/a/b"}, "/srv/data/a"},
+		{[]string{"/srv/data/a/b", "/srv/data/a/c", "/srv/data/ab"}, "/srv/data"},
+		{[]string{"/srv/a", "/var/b"}, ""},
+		{[]string{"a/b", "c/d"}, ""},
+	} {
+		if got := commonDir(tt.paths); got != tt.want {
+			t.Errorf("commonDir(%q) = %q; want %q", tt.paths, got, tt.want)
+		}
+	}
+}
+
+func TestCompactingReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := &compactingReporter{reporter: newReporter(outputNDJSON, &buf)}
+	r.Result(Result{Path: "/srv/data/a", Kind: resultLarge})
+	r.Result(Result{Path: "/srv/data/b/c", Kind: resultLarge})
+	if buf.Len() != 0 {
+		t.Errorf("compactingReporter passed on %q before flush; want nothing", buf.String())
+	}
+
+	r.Flush()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"path":"/srv/data/b/c"`) ||
+		!strings.Contains(lines[1], `"common_prefix":"/srv/data"`) {
+		t.Errorf("compactingReporter output = %q; want full paths with common prefix", buf.String())
+	}
+	if got := relativePath("/srv/data/b/c", "/srv/data"); got != "b/c" {
+		t.Errorf("relativePath() = %q; want b/c", got)
+	}
+}
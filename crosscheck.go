@@ -0,0 +1,112 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"log"
+	"path/filepath"
+
+	"github.com/dkorunic/findlargedir/fsinfo"
+)
+
+// Estimates off by more than this factor from used inode count are considered as disagreeing
+const crossCheckFactor = 10
+
+// A crossCheck compares summed directory estimates on a filesystem against its used inode count.
+type crossCheck struct {
+	Path      string `json:"path"`
+	Device    uint64 `json:"device"`
+	Label     string `json:"device_label"`
+	Estimated int64  `json:"estimated"`
+	UsedFiles uint64 `json:"used_files"`
+	Complete  bool   `json:"complete"`
+	Mismatch  bool   `json:"mismatch"`
+}
+
+// check decides whether estimates disagree with used inodes: too many estimated entries are always suspicious,
+// too few only when the whole filesystem was walked.
+func (c *crossCheck) check() {
+	if c.UsedFiles == 0 {
+		return
+	}
+
+	used := int64(c.UsedFiles)
+	c.Mismatch = c.Estimated > used*crossCheckFactor || (c.Complete && c.Estimated*crossCheckFactor < used)
+}
+
+// crossCheckSums sums estimates per device while walking a single root.
+type crossCheckSums struct {
+	devs   []uint64
+	checks map[uint64]*crossCheck
+}
+
+// rootSums holds estimate sums of the root currently being walked.
+var rootSums *crossCheckSums
+
+func newCrossCheckSums() *crossCheckSums {
+	return &crossCheckSums{checks: make(map[uint64]*crossCheck)}
+}
+
+// add accounts a directory estimate on a device, remembering the first directory seen on it.
+func (c *crossCheckSums) add(dev uint64, path string, estimate int64) {
+	if !*crossCheckFlag {
+		return
+	}
+
+	v, ok := c.checks[dev]
+	if !ok {
+		v = &crossCheck{Path: path, Device: dev}
+		c.checks[dev] = v
+		c.devs = append(c.devs, dev)
+	}
+	v.Estimated += estimate
+}
+
+// finish compares sums of every device against statfs used inode counts and warns about disagreements.
+func (c *crossCheckSums) finish() []crossCheck {
+	var res []crossCheck
+	for _, dev := range c.devs {
+		v := c.checks[dev]
+		usage, err := fsinfo.Statfs(v.Path)
+		if err != nil {
+			reportError(v.Path, err)
+			continue
+		}
+
+		v.UsedFiles = usage.UsedFiles()
+		v.Label = deviceLabel(dev, v.Path)
+		if info, err := fsCache.Get(dev, v.Path); err == nil {
+			abs, _ := filepath.Abs(v.Path)
+			v.Complete = info.Mountpoint == abs
+		}
+		v.check()
+
+		if v.Mismatch {
+			log.Printf("Estimated %v entries on %v disagree with %v used inodes, inode ratio is most likely incorrect for that filesystem.",
+				v.Estimated, redactedLabel(v.Label), v.UsedFiles)
+		} else {
+			log.Printf("Estimated %v entries on %v against %v used inodes.", v.Estimated, redactedLabel(v.Label), v.UsedFiles)
+		}
+		res = append(res, *v)
+	}
+	return res
+}
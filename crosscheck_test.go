@@ -0,0 +1,48 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestCrossCheck(t *testing.T) {
+	for _, tt := range []struct {
+		estimated int64
+		used      uint64
+		complete  bool
+		want      bool
+	}{
+		{1000, 1200, true, false},
+		{1000, 0, true, false},
+		{100000, 1200, false, true},
+		{10, 1200, false, false},
+		{10, 1200, true, true},
+	} {
+		c := crossCheck{Estimated: tt.estimated, UsedFiles: tt.used, Complete: tt.complete}
+		c.check()
+		if c.Mismatch != tt.want {
+			t.Errorf("crossCheck{%v, %v, %v} mismatch = %v; want %v", tt.estimated, tt.used, tt.complete,
+				c.Mismatch, tt.want)
+		}
+	}
+}
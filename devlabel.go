@@ -0,0 +1,62 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// deviceLabels maps device ids to labels, holding user supplied overrides and resolved mount points.
+var deviceLabels = make(map[uint64]string)
+
+// parseDeviceLabels registers user supplied device=label overrides.
+func parseDeviceLabels(list []string) error {
+	for _, v := range list {
+		i := strings.IndexByte(v, '=')
+		if i < 1 || i == len(v)-1 {
+			return fmt.Errorf("invalid device label %q, expected device=label", v)
+		}
+
+		dev, err := strconv.ParseUint(v[:i], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid device id in device label %q: %v", v, err)
+		}
+		deviceLabels[dev] = v[i+1:]
+	}
+	return nil
+}
+
+// deviceLabel returns a friendly name of a device, falling back to its mount point and then numeric id.
+func deviceLabel(dev uint64, path string) string {
+	if label, ok := deviceLabels[dev]; ok {
+		return label
+	}
+
+	label := strconv.FormatUint(dev, 10)
+	if info, err := fsCache.Get(dev, path); err == nil {
+		label = info.Mountpoint
+	}
+	deviceLabels[dev] = label
+	return label
+}
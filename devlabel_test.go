@@ -0,0 +1,45 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestParseDeviceLabels(t *testing.T) {
+	defer func() { deviceLabels = make(map[uint64]string) }()
+
+	if err := parseDeviceLabels([]string{"2050=data", "64768=/srv=x"}); err != nil {
+		t.Fatal(err)
+	}
+	for dev, want := range map[uint64]string{2050: "data", 64768: "/srv=x"} {
+		if got := deviceLabel(dev, "/nonexistent"); got != want {
+			t.Errorf("deviceLabel(%v) = %q; want %q", dev, got, want)
+		}
+	}
+
+	for _, v := range []string{"2050", "=data", "2050=", "sda=data"} {
+		if err := parseDeviceLabels([]string{v}); err == nil {
+			t.Errorf("parseDeviceLabels(%q) succeeded; want error", v)
+		}
+	}
+}
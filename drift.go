@@ -0,0 +1,73 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"log"
+	"math"
+)
+
+// A ratioDrift describes inode ratios observed on a device calibrated several times within a run, unstable if they
+// differ beyond verify-ratio-tolerance.
+type ratioDrift struct {
+	Device       uint64  `json:"device"`
+	Label        string  `json:"device_label"`
+	Calibrations int     `json:"calibrations"`
+	Min          float64 `json:"min_ratio"`
+	Max          float64 `json:"max_ratio"`
+	Unstable     bool    `json:"unstable"`
+}
+
+// addDrift compares a calibration against earlier ones of the same device, warning about drift beyond
+// verify-ratio-tolerance. Must be called before the calibration is recorded in the summary.
+func (s *Summary) addDrift(cal calibration, path string) {
+	if cal.Ratio <= 0 {
+		return
+	}
+
+	d := ratioDrift{Device: cal.Device, Label: deviceLabel(cal.Device, path), Calibrations: 1, Min: cal.Ratio,
+		Max: cal.Ratio}
+	for _, c := range s.Calibrations {
+		if c.Device == cal.Device && c.Ratio > 0 {
+			d.Calibrations++
+			d.Min, d.Max = math.Min(d.Min, c.Ratio), math.Max(d.Max, c.Ratio)
+		}
+	}
+	if d.Calibrations < 2 {
+		return
+	}
+
+	delta := ratioDelta(d.Min, d.Max)
+	d.Unstable = delta > *verifyRatioTolerance
+	if d.Unstable {
+		log.Printf("Warning: inode ratios of %v calibrations of device %v range from %v to %v (%.2f%% apart, above %v%%), filesystem might be unstable.",
+			d.Calibrations, redactedLabel(d.Label), d.Min, d.Max, delta, *verifyRatioTolerance)
+	}
+
+	for i := range s.Drift {
+		if s.Drift[i].Device == d.Device {
+			s.Drift[i] = d
+			return
+		}
+	}
+	s.Drift = append(s.Drift, d)
+}
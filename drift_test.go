@@ -0,0 +1,44 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestAddDrift(t *testing.T) {
+	var s Summary
+	for _, cal := range []calibration{{Device: 1, Ratio: 10}, {Device: 2, Ratio: 20}, {Device: 1, Ratio: 10.5}} {
+		s.addDrift(cal, "/nonexistent")
+		s.Calibrations = append(s.Calibrations, cal)
+	}
+	if len(s.Drift) != 1 || s.Drift[0].Device != 1 || s.Drift[0].Calibrations != 2 || s.Drift[0].Min != 10 ||
+		s.Drift[0].Max != 10.5 || s.Drift[0].Unstable {
+		t.Errorf("Drift = %+v; want stable drift of device 1 between 10 and 10.5", s.Drift)
+	}
+
+	cal := calibration{Device: 1, Ratio: 15}
+	s.addDrift(cal, "/nonexistent")
+	if len(s.Drift) != 1 || s.Drift[0].Calibrations != 3 || s.Drift[0].Max != 15 || !s.Drift[0].Unstable {
+		t.Errorf("Drift = %+v; want unstable drift of device 1 up to 15", s.Drift)
+	}
+}
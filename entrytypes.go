@@ -0,0 +1,60 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// entryTypes counts directory entries by their type.
+type entryTypes struct {
+	Files    int64 `json:"files"`
+	Dirs     int64 `json:"dirs"`
+	Symlinks int64 `json:"symlinks"`
+	Other    int64 `json:"other"`
+}
+
+// An entryBreakdown holds entry types of a directory counted in accurate mode.
+type entryBreakdown struct {
+	Path  string     `json:"path"`
+	Types entryTypes `json:"types"`
+}
+
+// add accounts a single entry of a given file mode type.
+func (t *entryTypes) add(mode os.FileMode) {
+	switch {
+	case mode.IsRegular():
+		t.Files++
+	case mode.IsDir():
+		t.Dirs++
+	case mode&os.ModeSymlink != 0:
+		t.Symlinks++
+	default:
+		t.Other++
+	}
+}
+
+func (t entryTypes) String() string {
+	return fmt.Sprintf("%v files, %v directories, %v symlinks, %v other", formatCount(t.Files), formatCount(t.Dirs),
+		formatCount(t.Symlinks), formatCount(t.Other))
+}
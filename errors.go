@@ -0,0 +1,71 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// errorRecord is a machine-parseable per-path error report.
+type errorRecord struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+	Errno int    `json:"errno"`
+}
+
+var errorMutex sync.Mutex
+var errorEncoder = json.NewEncoder(os.Stderr)
+
+// reportError will display an error for a given path, either as a free text or as a JSON record on stderr.
+func reportError(path string, err error) {
+	defer failFast()
+
+	if !*errorsJSONFlag {
+		log.Print(redactedError(path, err))
+		return
+	}
+
+	record := errorRecord{Path: redacted(path), Error: redactedError(path, err)}
+
+	// Errno is zero when underlying error didn't originate from a syscall
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		record.Errno = int(errno)
+	}
+
+	errorMutex.Lock()
+	defer errorMutex.Unlock()
+	_ = errorEncoder.Encode(&record)
+}
+
+// failFast aborts the program run on the first error, if requested.
+func failFast() {
+	if *failFastFlag {
+		log.Printf("Exiting on first error as requested.")
+		exit(1)
+	}
+}
@@ -0,0 +1,58 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestReportErrorJSON(t *testing.T) {
+	var buf bytes.Buffer
+	savedEncoder := errorEncoder
+	defer func() { errorEncoder, *errorsJSONFlag = savedEncoder, false }()
+	errorEncoder, *errorsJSONFlag = json.NewEncoder(&buf), true
+
+	for _, tt := range []struct {
+		err  error
+		want errorRecord
+	}{
+		{&os.PathError{Op: "open", Path: "/srv/data", Err: syscall.EACCES},
+			errorRecord{Path: "/srv/data", Error: "open /srv/data: permission denied", Errno: int(syscall.EACCES)}},
+		{errors.New("calibration failed"), errorRecord{Path: "/srv/data", Error: "calibration failed"}},
+	} {
+		buf.Reset()
+		reportError("/srv/data", tt.err)
+
+		var got errorRecord
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("reportError() wrote %q: %v", buf.String(), err)
+		}
+		if got != tt.want {
+			t.Errorf("reportError(%v) record = %+v; want %+v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dkorunic/findlargedir/fsinfo"
+)
+
+// explained holds devices already planned for calibration, mirroring ratioCache.
+var explained = make(map[uint64]bool)
+
+// explainRoot displays planned calibrations of a single root without creating any files.
+func explainRoot(rootPath string) {
+	rootStat, err := os.Lstat(rootPath)
+	if err != nil {
+		reportError(rootPath, err)
+		return
+	}
+
+	if countingMode {
+		log.Printf("Explain: entries in %q would be counted, no calibration is done on this platform.", redacted(rootPath))
+		return
+	}
+
+	explainCalibration(rootStat, rootPath)
+
+	// Roots known to be filesystems never get checked for crossing filesystem boundaries
+	if *rootsAreFilesystemsFlag && !*oneFilesystemFlag {
+		return
+	}
+
+	mounts, err := fsinfo.Mounts()
+	if err != nil {
+		log.Printf("Explain: unable to list mount points below %q: %v", redacted(rootPath), err)
+		return
+	}
+
+	absRoot, err := filepath.Abs(rootPath)
+	if err != nil {
+		reportError(rootPath, err)
+		return
+	}
+
+	// Parents come before their nested mount points
+	sort.Slice(mounts, func(i, j int) bool {
+		return mounts[i].Mountpoint < mounts[j].Mountpoint
+	})
+
+	var skipped []string
+	seen := make(map[string]bool)
+	for _, m := range mounts {
+		if !isSubpath(absRoot, m.Mountpoint) || isUnderAny(skipped, m.Mountpoint) || seen[m.Mountpoint] {
+			continue
+		}
+
+		// Only the topmost of filesystems mounted over each other is reachable by the walk
+		fi, err := os.Stat(m.Mountpoint)
+		if err != nil || isSameFilesystem(rootStat, fi) || m.Dev != 0 && m.Dev != getDev(fi) {
+			continue
+		}
+		seen[m.Mountpoint] = true
+
+		if *oneFilesystemFlag {
+			log.Printf("Explain: mount point %q (%v filesystem) would be skipped in onefilesystem mode.",
+				redacted(m.Mountpoint), m.FSType)
+			skipped = append(skipped, m.Mountpoint)
+			continue
+		}
+
+		// Same as nestedCalibration, pseudo and network filesystems are never written to
+		if info, err := fsCache.Get(getDev(fi), m.Mountpoint); err == nil && !info.IsLocal() {
+			log.Printf("Explain: %v would not be calibrated, no files written, checked with the ratio of the filesystem it is mounted on instead.",
+				fsDescription(fi, m.Mountpoint))
+			continue
+		}
+		explainCalibration(fi, m.Mountpoint)
+	}
+}
+
+// explainCalibration displays what calibration of a filesystem would create, once per device.
+func explainCalibration(fi os.FileInfo, path string) {
+	dev := getDev(fi)
+	if explained[dev] && !*rootsAreFilesystemsFlag {
+		log.Printf("Explain: %v is already calibrated, its ratio would be reused for %q.", fsDescription(fi, path), redacted(path))
+		return
+	}
+	explained[dev] = true
+
+	// Calibration directory and file count are chosen the same way as when calibrating
+	dir, ok := calibrationDir(path, dev)
+	if !ok {
+		log.Printf("Explain: %v would not be calibrated, no writable directory outside of scan roots.",
+			fsDescription(fi, path))
+		return
+	}
+	count, ok := calibrationFileCount(dir)
+	if !ok {
+		log.Printf("Explain: %v would not be calibrated, not enough free inodes.", fsDescription(fi, path))
+		return
+	}
+
+	log.Printf("Explain: would calibrate %v in temporary directory %q, creating %v files of %v bytes (%v bytes in total)%v.",
+		fsDescription(fi, path), redacted(filepath.Join(dir, testDirName+"*")), count, len(testContent),
+		count*int64(len(testContent)), verifyNote())
+
+	stale, err := findStaleCalibration(dir, time.Now().Add(-staleCalibrationAge))
+	if err == nil && len(stale) > 0 {
+		action := "reported"
+		if *cleanStaleCalibrationFlag {
+			action = "removed"
+		}
+		log.Printf("Explain: %v stale calibration directories in %q would be %v.", len(stale), redacted(dir), action)
+	}
+}
+
+// verifyNote describes repeated calibration with verify-ratio.
+func verifyNote() string {
+	if !*verifyRatioFlag {
+		return ""
+	}
+	return ", twice in independent temporary directories to verify the ratio"
+}
+
+// isSubpath checks if path is strictly below root.
+func isSubpath(root, path string) bool {
+	if root == string(filepath.Separator) {
+		return len(path) > 1 && strings.HasPrefix(path, root)
+	}
+	return strings.HasPrefix(path, root+string(filepath.Separator))
+}
+
+// isUnderAny checks if path is below any of given parents.
+func isUnderAny(parents []string, path string) bool {
+	for _, p := range parents {
+		if isSubpath(p, path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestIsSubpath(t *testing.T) {
+	for _, tt := range []struct {
+		root, path string
+		want       bool
+	}{
+		{"/", "/proc", true},
+		{"/", "/", false},
+		{"/srv", "/srv/data", true},
+		{"/srv", "/srv", false},
+		{"/srv", "/srvdata", false},
+		{"/srv", "/var", false},
+	} {
+		if got := isSubpath(tt.root, tt.path); got != tt.want {
+			t.Errorf("isSubpath(%q, %q) = %v; want %v", tt.root, tt.path, got, tt.want)
+		}
+	}
+
+	if !isUnderAny([]string{"/proc", "/sys"}, "/sys/fs/cgroup") || isUnderAny([]string{"/proc"}, "/sys") {
+		t.Error("isUnderAny() mismatched nested mount points")
+	}
+}
+
+func TestExplainCalibration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "explain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	savedCount := *testFileCount
+	defer func() {
+		log.SetOutput(os.Stderr)
+		*testFileCount, *maxCalibrationFiles, *verifyRatioFlag = savedCount, 0, false
+		delete(explained, getDev(fi))
+	}()
+	*testFileCount, *maxCalibrationFiles, *verifyRatioFlag = 20000, 5000, true
+
+	explainCalibration(fi, dir)
+	if got := buf.String(); !strings.Contains(got, "creating 5000 files") || !strings.Contains(got, "twice") {
+		t.Errorf("explainCalibration() logged %q; want capped file count calibrated twice", got)
+	}
+}
+
+func TestExplainRootPseudo(t *testing.T) {
+	if _, err := os.Stat("/proc/self"); err != nil {
+		t.Skip("no procfs mounted")
+	}
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		explained = make(map[uint64]bool)
+	}()
+
+	explainRoot("/")
+	got := buf.String()
+	if strings.Count(got, `proc filesystem on "/proc" would not be calibrated, no files written`) != 1 ||
+		strings.Contains(got, "calibrate proc filesystem") {
+		t.Errorf("explainRoot() logged %q; want /proc listed once as checked with a borrowed ratio, no files written", got)
+	}
+}
@@ -0,0 +1,100 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/karrick/godirwalk"
+)
+
+const defaultFanoutThreshold = 10000
+
+// A fanout is an extrapolated estimate for a directory with a very large number of subdirectories.
+type fanout struct {
+	Subdirs  int
+	Sampled  int
+	Estimate int64
+}
+
+// sampleFanout reads subdirectories of a directory and, if there are at least threshold of them, estimates total
+// entries held by all subdirectories by measuring a random sample of n subdirectories.
+func sampleFanout(path string, ratio float64, n, threshold int) (fanout, bool, error) {
+	deChildren, err := godirwalk.ReadDirents(path, nil)
+	if err != nil {
+		return fanout{}, false, err
+	}
+
+	var subdirs []string
+	for _, de := range deChildren {
+		if de.IsDir() {
+			subdirs = append(subdirs, filepath.Join(path, de.Name()))
+		}
+	}
+	if len(subdirs) < threshold {
+		return fanout{}, false, nil
+	}
+
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	// Subdirectories vanishing in the meantime are just not accounted for
+	var total int64
+	f := fanout{Subdirs: len(subdirs)}
+	for _, v := range sampleNames(subdirs, n, r) {
+		fi, err := os.Stat(v)
+		if err != nil {
+			continue
+		}
+		total += int64(float64(fi.Size()) / ratio)
+		f.Sampled++
+	}
+	if f.Sampled == 0 {
+		return fanout{}, false, nil
+	}
+
+	f.Estimate = extrapolate(total, f.Sampled, f.Subdirs)
+	return f, true, nil
+}
+
+// sampleNames picks n distinct random names with a partial Fisher-Yates shuffle, reordering names in place.
+func sampleNames(names []string, n int, r *rand.Rand) []string {
+	if n >= len(names) {
+		return names
+	}
+
+	for i := 0; i < n; i++ {
+		j := i + r.Intn(len(names)-i)
+		names[i], names[j] = names[j], names[i]
+	}
+	return names[:n]
+}
+
+// extrapolate scales entry count total measured over sampled directories to all directories.
+func extrapolate(total int64, sampled, all int) int64 {
+	if sampled == 0 {
+		return 0
+	}
+	return int64(float64(total) / float64(sampled) * float64(all))
+}
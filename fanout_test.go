@@ -0,0 +1,102 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestSampleNames(t *testing.T) {
+	names := make([]string, 100)
+	for i := range names {
+		names[i] = strconv.Itoa(i)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	got := sampleNames(names, 10, r)
+	if len(got) != 10 {
+		t.Fatalf("sampleNames() returned %v names; want 10", len(got))
+	}
+
+	seen := make(map[string]bool)
+	for _, v := range got {
+		if seen[v] {
+			t.Errorf("sampleNames() returned duplicate name %q", v)
+		}
+		seen[v] = true
+	}
+
+	if got := sampleNames(names[:5], 10, r); len(got) != 5 {
+		t.Errorf("sampleNames() with n larger than names returned %v names; want 5", len(got))
+	}
+}
+
+func TestExtrapolate(t *testing.T) {
+	cases := []struct {
+		total        int64
+		sampled, all int
+		want         int64
+	}{
+		{total: 1000, sampled: 10, all: 1000, want: 100000},
+		{total: 0, sampled: 10, all: 1000, want: 0},
+		{total: 1000, sampled: 0, all: 1000, want: 0},
+	}
+	for _, tc := range cases {
+		if got := extrapolate(tc.total, tc.sampled, tc.all); got != tc.want {
+			t.Errorf("extrapolate(%v, %v, %v) = %v; want %v", tc.total, tc.sampled, tc.all, got, tc.want)
+		}
+	}
+}
+
+func TestSampleFanout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fanout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i := 0; i < 20; i++ {
+		if err := os.Mkdir(filepath.Join(dir, strconv.Itoa(i)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, err := sampleFanout(dir, 1, 5, 21); ok || err != nil {
+		t.Errorf("sampleFanout() below threshold = %v, %v; want false, nil", ok, err)
+	}
+
+	f, ok, err := sampleFanout(dir, 1, 5, 20)
+	if !ok || err != nil {
+		t.Fatalf("sampleFanout() at threshold = %v, %v; want true, nil", ok, err)
+	}
+	if f.Subdirs != 20 || f.Sampled != 5 || f.Estimate <= 0 {
+		t.Errorf("sampleFanout() = %+v; want 20 subdirs, 5 sampled and a positive estimate", f)
+	}
+}
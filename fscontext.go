@@ -0,0 +1,81 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/dkorunic/findlargedir/fsinfo"
+)
+
+// An fsContext describes filesystem holding a flagged directory, sizes in bytes.
+type fsContext struct {
+	Mountpoint string `json:"mountpoint"`
+	Size       uint64 `json:"size_bytes"`
+	Free       uint64 `json:"free_bytes"`
+	Inodes     uint64 `json:"inodes"`
+	FreeInodes uint64 `json:"free_inodes"`
+}
+
+// fsContexts caches filesystem context per device, nil if unknown.
+var fsContexts = make(map[uint64]*fsContext)
+
+// filesystemContext returns cached mount point and free space of filesystem a directory resides on.
+func filesystemContext(dev uint64, path string) *fsContext {
+	if c, ok := fsContexts[dev]; ok {
+		return c
+	}
+
+	var c *fsContext
+	if usage, err := fsinfo.Statfs(path); err == nil {
+		c = &fsContext{Size: usage.Size, Free: usage.Free, Inodes: usage.Files, FreeInodes: usage.FreeFiles}
+		if info, err := fsCache.Get(dev, path); err == nil {
+			c.Mountpoint = info.Mountpoint
+		}
+	}
+	fsContexts[dev] = c
+	return c
+}
+
+// fsNote describes filesystem holding a directory, if known.
+func fsNote(r Result) string {
+	c := r.Filesystem
+	if c == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (on %q, %v of %v free, %v of %v inodes free)", c.Mountpoint, humanBytes(c.Free),
+		humanBytes(c.Size), c.FreeInodes, c.Inodes)
+}
+
+// humanBytes will display a size in bytes with binary unit prefix.
+func humanBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%vB", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
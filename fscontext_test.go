@@ -0,0 +1,42 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestFSNote(t *testing.T) {
+	for n, want := range map[uint64]string{512: "512B", 1536: "1.5KiB", 10 << 30: "10.0GiB"} {
+		if got := humanBytes(n); got != want {
+			t.Errorf("humanBytes(%v) = %q; want %q", n, got, want)
+		}
+	}
+
+	r := Result{Filesystem: &fsContext{Mountpoint: "/var", Size: 100 << 30, Free: 5 << 30, Inodes: 1000, FreeInodes: 10}}
+	if got, want := fsNote(r), ` (on "/var", 5.0GiB of 100.0GiB free, 10 of 1000 inodes free)`; got != want {
+		t.Errorf("fsNote() = %q; want %q", got, want)
+	}
+	if got := fsNote(Result{}); got != "" {
+		t.Errorf("fsNote() without filesystem = %q; want empty", got)
+	}
+}
@@ -0,0 +1,197 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package fsinfo provides filesystem type and mountpoint resolution for device
+// ids, with a bounded concurrency-safe LRU cache to avoid repeated lookups.
+package fsinfo
+
+import (
+	"container/list"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound is returned when no mounted filesystem matches a device id.
+var ErrNotFound = errors.New("fsinfo: filesystem not found")
+
+// An Info describes a single mounted filesystem. Root is the directory within the filesystem mounted at Mountpoint,
+// which differs from / for bind mounts (known only on Linux).
+type Info struct {
+	Dev        uint64
+	Mountpoint string
+	FSType     string
+	Source     string
+	Options    string
+	Root       string
+}
+
+// Filesystem types not backed by storage, i.e. kernel interfaces.
+var pseudoTypes = map[string]bool{
+	"autofs": true, "binfmt_misc": true, "bpf": true, "cgroup": true, "cgroup2": true, "configfs": true,
+	"debugfs": true, "devfs": true, "devpts": true, "devtmpfs": true, "efivarfs": true, "fdescfs": true,
+	"fusectl": true, "hugetlbfs": true, "linprocfs": true, "linsysfs": true, "mqueue": true, "nsfs": true,
+	"proc": true, "procfs": true, "pstore": true, "rpc_pipefs": true, "securityfs": true, "selinuxfs": true,
+	"sysfs": true, "tracefs": true,
+}
+
+// Filesystem types residing on remote hosts.
+var networkTypes = map[string]bool{
+	"9p": true, "afs": true, "beegfs": true, "ceph": true, "cifs": true, "davfs": true, "fuse.glusterfs": true,
+	"fuse.rclone": true, "fuse.s3fs": true, "fuse.sshfs": true, "glusterfs": true, "gpfs": true, "lustre": true,
+	"ncpfs": true, "nfs": true, "nfs4": true, "smb3": true, "smbfs": true,
+}
+
+// Filesystem types backed by memory.
+var ramTypes = map[string]bool{"tmpfs": true, "ramfs": true}
+
+// IsPseudo checks if filesystem is not backed by storage.
+func (i Info) IsPseudo() bool {
+	return pseudoTypes[i.FSType]
+}
+
+// IsNetwork checks if filesystem resides on a remote host.
+func (i Info) IsNetwork() bool {
+	return networkTypes[i.FSType]
+}
+
+// IsRAM checks if filesystem is backed by memory, so its entries consume RAM.
+func (i Info) IsRAM() bool {
+	return ramTypes[i.FSType]
+}
+
+// IsOverlay checks if filesystem is an overlay merging other directories.
+func (i Info) IsOverlay() bool {
+	return i.FSType == "overlay"
+}
+
+// OverlayDirs returns upper and lower directories of an overlay filesystem from its mount options, if known.
+func (i Info) OverlayDirs() (upper string, lower []string) {
+	for _, opt := range strings.Split(i.Options, ",") {
+		switch {
+		case strings.HasPrefix(opt, "upperdir="):
+			upper = strings.TrimPrefix(opt, "upperdir=")
+		case strings.HasPrefix(opt, "lowerdir="):
+			lower = strings.Split(strings.TrimPrefix(opt, "lowerdir="), ":")
+		}
+	}
+	return upper, lower
+}
+
+// IsLocal checks if filesystem is backed by local storage.
+func (i Info) IsLocal() bool {
+	return !i.IsPseudo() && !i.IsNetwork()
+}
+
+// A Usage describes filesystem inode and space usage, with sizes in bytes.
+type Usage struct {
+	Files     uint64
+	FreeFiles uint64
+	Size      uint64
+	Free      uint64
+}
+
+// UsedFiles returns number of inodes in use, or 0 if the filesystem doesn't
+// report inode counts (i.e. dynamically allocated inodes).
+func (u Usage) UsedFiles() uint64 {
+	if u.Files < u.FreeFiles {
+		return 0
+	}
+	return u.Files - u.FreeFiles
+}
+
+// A LookupFunc resolves filesystem information for a device id, using path
+// residing on that device as a hint.
+type LookupFunc func(dev uint64, path string) (Info, error)
+
+// A Cache is a bounded LRU of filesystem information keyed by device id.
+//
+// A Cache should be created with NewCache()
+type Cache struct {
+	mu     sync.Mutex
+	size   int
+	ll     *list.List
+	items  map[uint64]*list.Element
+	lookup LookupFunc
+}
+
+// NewCache creates a new Cache holding at most size entries, using Lookup for
+// resolution of unseen device ids.
+func NewCache(size int) *Cache {
+	return NewCacheWithLookup(size, Lookup)
+}
+
+// NewCacheWithLookup creates a new Cache holding at most size entries, using
+// lookup for resolution of unseen device ids.
+func NewCacheWithLookup(size int, lookup LookupFunc) *Cache {
+	if size < 1 {
+		size = 1
+	}
+	return &Cache{
+		size:   size,
+		ll:     list.New(),
+		items:  make(map[uint64]*list.Element, size),
+		lookup: lookup,
+	}
+}
+
+// Get returns filesystem information for a device id, resolving and caching it
+// when the device id is unseen. Failed lookups are not cached.
+func (c *Cache) Get(dev uint64, path string) (Info, error) {
+	c.mu.Lock()
+	if e, ok := c.items[dev]; ok {
+		c.ll.MoveToFront(e)
+		info := e.Value.(Info)
+		c.mu.Unlock()
+		return info, nil
+	}
+	c.mu.Unlock()
+
+	// Resolve outside of the lock as it might be slow
+	info, err := c.lookup(dev, path)
+	if err != nil {
+		return Info{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[dev]; ok {
+		c.ll.MoveToFront(e)
+		return e.Value.(Info), nil
+	}
+
+	c.items[dev] = c.ll.PushFront(info)
+	if c.ll.Len() > c.size {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.items, e.Value.(Info).Dev)
+	}
+
+	return info, nil
+}
+
+// Len returns the number of cached entries.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
@@ -0,0 +1,95 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// +build darwin freebsd
+
+package fsinfo
+
+import (
+	"bytes"
+
+	"golang.org/x/sys/unix"
+)
+
+// Lookup resolves filesystem information for a device id from statfs(2) of
+// path, which already carries filesystem type and mountpoint on BSD systems.
+func Lookup(dev uint64, path string) (Info, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return Info{}, err
+	}
+
+	return Info{
+		Dev:        dev,
+		Mountpoint: cString(st.Mntonname[:]),
+		FSType:     cString(st.Fstypename[:]),
+		Source:     cString(st.Mntfromname[:]),
+	}, nil
+}
+
+// Mounts returns all mounted filesystems from getfsstat(2), resolving device ids of their mountpoints.
+func Mounts() ([]Info, error) {
+	n, err := unix.Getfsstat(nil, unix.MNT_NOWAIT)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]unix.Statfs_t, n)
+	n, err = unix.Getfsstat(buf, unix.MNT_NOWAIT)
+	if err != nil {
+		return nil, err
+	}
+
+	mounts := make([]Info, 0, n)
+	for _, st := range buf[:n] {
+		info := Info{
+			Mountpoint: cString(st.Mntonname[:]),
+			FSType:     cString(st.Fstypename[:]),
+			Source:     cString(st.Mntfromname[:]),
+		}
+
+		var sst unix.Stat_t
+		if err := unix.Stat(info.Mountpoint, &sst); err != nil {
+			continue
+		}
+		info.Dev = uint64(sst.Dev)
+		mounts = append(mounts, info)
+	}
+	return mounts, nil
+}
+
+// Statfs returns inode and space usage of the filesystem path resides on.
+func Statfs(path string) (Usage, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return Usage{}, err
+	}
+	return Usage{Files: uint64(st.Files), FreeFiles: uint64(st.Ffree), Size: uint64(st.Blocks) * uint64(st.Bsize),
+		Free: uint64(st.Bavail) * uint64(st.Bsize)}, nil
+}
+
+// cString converts a NUL-terminated byte array to a string.
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
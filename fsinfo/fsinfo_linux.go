@@ -0,0 +1,170 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// +build linux
+
+package fsinfo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const mountinfoPath = "/proc/self/mountinfo"
+
+// Lookup resolves filesystem information for a device id from mountinfo. When
+// several mounts share the device id (i.e. bind mounts), the one with the
+// longest mountpoint prefix of path is preferred.
+func Lookup(dev uint64, path string) (Info, error) {
+	f, err := os.Open(mountinfoPath)
+	if err != nil {
+		return Info{}, err
+	}
+	defer f.Close()
+
+	mounts, err := parseMountinfo(f)
+	if err != nil {
+		return Info{}, err
+	}
+
+	return matchMount(mounts, dev, path)
+}
+
+// Mounts returns all mounted filesystems from mountinfo.
+func Mounts() ([]Info, error) {
+	f, err := os.Open(mountinfoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseMountinfo(f)
+}
+
+// Statfs returns inode and space usage of the filesystem path resides on.
+func Statfs(path string) (Usage, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return Usage{}, err
+	}
+	return Usage{Files: st.Files, FreeFiles: st.Ffree, Size: st.Blocks * uint64(st.Bsize),
+		Free: st.Bavail * uint64(st.Bsize)}, nil
+}
+
+// matchMount picks the best matching mount for a device id and path.
+func matchMount(mounts []Info, dev uint64, path string) (Info, error) {
+	var best Info
+	var found, bestPrefix bool
+	for _, m := range mounts {
+		if m.Dev != dev {
+			continue
+		}
+
+		prefix := hasPathPrefix(path, m.Mountpoint)
+		if !found || (prefix && (!bestPrefix || len(m.Mountpoint) > len(best.Mountpoint))) {
+			best, found, bestPrefix = m, true, prefix
+		}
+	}
+
+	if !found {
+		return Info{}, ErrNotFound
+	}
+	return best, nil
+}
+
+// parseMountinfo parses proc(5) mountinfo format.
+func parseMountinfo(r io.Reader) ([]Info, error) {
+	var mounts []Info
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+		fields := strings.Fields(s.Text())
+		if len(fields) < 10 {
+			continue
+		}
+
+		// Optional fields are terminated by a single hyphen
+		sep := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 0 || sep+2 >= len(fields) {
+			continue
+		}
+
+		var major, minor uint32
+		if _, err := fmt.Sscanf(fields[2], "%d:%d", &major, &minor); err != nil {
+			continue
+		}
+
+		info := Info{
+			Dev:        unix.Mkdev(major, minor),
+			Mountpoint: unescapeOctal(fields[4]),
+			FSType:     fields[sep+1],
+			Source:     unescapeOctal(fields[sep+2]),
+			Root:       unescapeOctal(fields[3]),
+		}
+		if sep+3 < len(fields) {
+			info.Options = unescapeOctal(fields[sep+3])
+		}
+		mounts = append(mounts, info)
+	}
+
+	return mounts, s.Err()
+}
+
+// unescapeOctal decodes octal escapes (such as \040 for space) used in mountinfo paths.
+func unescapeOctal(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// hasPathPrefix checks if path equals prefix or is located under it.
+func hasPathPrefix(path, prefix string) bool {
+	if prefix == "/" || path == prefix {
+		return true
+	}
+	return strings.HasPrefix(path, prefix+"/")
+}
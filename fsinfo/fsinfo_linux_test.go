@@ -0,0 +1,89 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// +build linux
+
+package fsinfo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+const testMountinfo = `22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+23 22 0:21 / /proc rw,nosuid,nodev,noexec,relatime shared:12 - proc proc rw
+24 22 8:2 / /srv/my\040data rw,relatime shared:2 - xfs /dev/sda2 rw,attr2
+25 22 8:2 /export /home/export rw,relatime shared:2 - xfs /dev/sda2 rw,attr2
+26 22 0:50 / /var/lib/docker/merged rw,relatime - overlay overlay rw,lowerdir=/l1:/l2,upperdir=/up,workdir=/work
+bogus line
+`
+
+func TestParseMountinfo(t *testing.T) {
+	mounts, err := parseMountinfo(strings.NewReader(testMountinfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mounts) != 5 {
+		t.Fatalf("parseMountinfo() returned %v mounts; want 5", len(mounts))
+	}
+
+	want := Info{Dev: unix.Mkdev(8, 2), Mountpoint: "/srv/my data", FSType: "xfs", Source: "/dev/sda2",
+		Options: "rw,attr2", Root: "/"}
+	if mounts[2] != want {
+		t.Errorf("parseMountinfo()[2] = %+v; want %+v", mounts[2], want)
+	}
+	if mounts[3].Root != "/export" {
+		t.Errorf("parseMountinfo()[3] root = %q; want /export", mounts[3].Root)
+	}
+
+	upper, lower := mounts[4].OverlayDirs()
+	if !mounts[4].IsOverlay() || upper != "/up" || !reflect.DeepEqual(lower, []string{"/l1", "/l2"}) {
+		t.Errorf("parseMountinfo()[4] overlay directories = %q, %q; want /up and [/l1 /l2]", upper, lower)
+	}
+}
+
+func TestMatchMount(t *testing.T) {
+	mounts, _ := parseMountinfo(strings.NewReader(testMountinfo))
+
+	cases := []struct {
+		dev  uint64
+		path string
+		want string
+	}{
+		{dev: unix.Mkdev(8, 1), path: "/usr/lib", want: "/"},
+		{dev: unix.Mkdev(8, 2), path: "/home/export/user", want: "/home/export"},
+		{dev: unix.Mkdev(8, 2), path: "/srv/my data/x", want: "/srv/my data"},
+		{dev: unix.Mkdev(8, 2), path: "/elsewhere", want: "/srv/my data"},
+	}
+	for _, tc := range cases {
+		info, err := matchMount(mounts, tc.dev, tc.path)
+		if err != nil || info.Mountpoint != tc.want {
+			t.Errorf("matchMount(%v, %q) = %q, %v; want %q", tc.dev, tc.path, info.Mountpoint, err, tc.want)
+		}
+	}
+
+	if _, err := matchMount(mounts, unix.Mkdev(9, 9), "/"); err != ErrNotFound {
+		t.Errorf("matchMount() for unknown device error = %v; want %v", err, ErrNotFound)
+	}
+}
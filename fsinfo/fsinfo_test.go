@@ -0,0 +1,98 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fsinfo
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestCacheGet(t *testing.T) {
+	calls := make(map[uint64]int)
+	var mu sync.Mutex
+	c := NewCacheWithLookup(2, func(dev uint64, path string) (Info, error) {
+		mu.Lock()
+		calls[dev]++
+		mu.Unlock()
+		return Info{Dev: dev, Mountpoint: path, FSType: "ext4"}, nil
+	})
+
+	for i := 0; i < 3; i++ {
+		info, err := c.Get(1, "/one")
+		if err != nil || info.Mountpoint != "/one" {
+			t.Fatalf("c.Get(1) = %+v, %v; want /one, nil", info, err)
+		}
+	}
+	if calls[1] != 1 {
+		t.Errorf("lookup called %v times for a cached device; want 1", calls[1])
+	}
+
+	// Device 1 is most recently used, so device 2 gets evicted by device 3
+	_, _ = c.Get(2, "/two")
+	_, _ = c.Get(1, "/one")
+	_, _ = c.Get(3, "/three")
+	_, _ = c.Get(1, "/one")
+	_, _ = c.Get(2, "/two")
+
+	if c.Len() != 2 {
+		t.Errorf("c.Len() = %v; want 2", c.Len())
+	}
+	if calls[1] != 1 || calls[2] != 2 || calls[3] != 1 {
+		t.Errorf("lookup calls = %v; want map[1:1 2:2 3:1]", calls)
+	}
+}
+
+func TestCacheGetError(t *testing.T) {
+	errLookup := errors.New("fsinfo_test: lookup")
+	calls := 0
+	c := NewCacheWithLookup(4, func(dev uint64, path string) (Info, error) {
+		calls++
+		return Info{}, errLookup
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Get(1, "/"); err != errLookup {
+			t.Errorf("c.Get() error = %v; want %v", err, errLookup)
+		}
+	}
+	if calls != 2 || c.Len() != 0 {
+		t.Errorf("failed lookups should not be cached: calls = %v, len = %v", calls, c.Len())
+	}
+}
+
+func TestInfoIsRAM(t *testing.T) {
+	for fstype, want := range map[string]bool{"tmpfs": true, "ramfs": true, "ext4": false, "devtmpfs": false} {
+		if got := (Info{FSType: fstype}).IsRAM(); got != want {
+			t.Errorf("Info{FSType: %q}.IsRAM() = %v; want %v", fstype, got, want)
+		}
+	}
+}
+
+func TestInfoIsLocal(t *testing.T) {
+	for fstype, want := range map[string]bool{"ext4": true, "xfs": true, "tmpfs": true, "proc": false,
+		"cgroup2": false, "nfs4": false, "fuse.sshfs": false, "fuse": true} {
+		if got := (Info{FSType: fstype}).IsLocal(); got != want {
+			t.Errorf("Info{FSType: %q}.IsLocal() = %v; want %v", fstype, got, want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// fsTypeThresholds maps filesystem types to large directory thresholds overriding the global one.
+var fsTypeThresholds = make(map[string]int64)
+
+// parseFSTypeThresholds registers user supplied fstype=count thresholds.
+func parseFSTypeThresholds(list []string) error {
+	for _, v := range list {
+		i := strings.IndexByte(v, '=')
+		if i < 1 || i == len(v)-1 {
+			return fmt.Errorf("invalid filesystem type threshold %q, expected fstype=count", v)
+		}
+
+		n, err := strconv.ParseInt(v[i+1:], 10, 64)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid count in filesystem type threshold %q", v)
+		}
+		fsTypeThresholds[v[:i]] = n
+	}
+	return nil
+}
+
+// thresholdFor returns large directory threshold of the filesystem holding a directory, falling back to tmpfs-threshold
+// on memory backed filesystems and then to the global one.
+func thresholdFor(dev uint64, path string) int64 {
+	if len(fsTypeThresholds) == 0 && *tmpfsThreshold == 0 {
+		return *alertThreshold
+	}
+
+	if info, err := fsCache.Get(dev, path); err == nil {
+		if n, ok := fsTypeThresholds[info.FSType]; ok {
+			return n
+		}
+		if *tmpfsThreshold > 0 && info.IsRAM() {
+			return *tmpfsThreshold
+		}
+	}
+	return *alertThreshold
+}
+
+// sameThresholds checks if two sets of filesystem type thresholds are equal.
+func sameThresholds(a, b map[string]int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if n, ok := b[k]; !ok || n != v {
+			return false
+		}
+	}
+	return true
+}
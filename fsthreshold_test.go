@@ -0,0 +1,86 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseFSTypeThresholds(t *testing.T) {
+	defer func() { fsTypeThresholds = make(map[string]int64) }()
+
+	if err := parseFSTypeThresholds([]string{"ext4=50000", "tmpfs=200000"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int64{"ext4": 50000, "tmpfs": 200000}; !sameThresholds(fsTypeThresholds, want) {
+		t.Errorf("parseFSTypeThresholds() = %v; want %v", fsTypeThresholds, want)
+	}
+
+	for _, v := range []string{"ext4", "=100", "ext4=", "ext4=many", "ext4=0"} {
+		if err := parseFSTypeThresholds([]string{v}); err == nil {
+			t.Errorf("parseFSTypeThresholds(%q) succeeded; want error", v)
+		}
+	}
+}
+
+func TestThresholdFor(t *testing.T) {
+	defer func() { fsTypeThresholds = make(map[string]int64) }()
+
+	dir := os.TempDir()
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := fsCache.Get(getDev(fi), dir)
+	if err != nil {
+		t.Skip(err)
+	}
+
+	fsTypeThresholds["nonexistentfs"] = 1
+	if got := thresholdFor(getDev(fi), dir); got != *alertThreshold {
+		t.Errorf("thresholdFor(%q) without matching fstype = %v; want global %v", dir, got, *alertThreshold)
+	}
+	fsTypeThresholds[info.FSType] = 123
+	if got := thresholdFor(getDev(fi), dir); got != 123 {
+		t.Errorf("thresholdFor(%q) on %v = %v; want 123", dir, info.FSType, got)
+	}
+}
+
+func TestThresholdForTmpfs(t *testing.T) {
+	defer func() { *tmpfsThreshold = 0 }()
+
+	dir := "/dev/shm"
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		t.Skip(err)
+	}
+	info, err := fsCache.Get(getDev(fi), dir)
+	if err != nil || !info.IsRAM() {
+		t.Skipf("%v is not on a memory backed filesystem", dir)
+	}
+
+	*tmpfsThreshold = 42
+	if got := thresholdFor(getDev(fi), dir); got != 42 {
+		t.Errorf("thresholdFor(%q) on %v = %v; want 42", dir, info.FSType, got)
+	}
+}
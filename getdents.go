@@ -0,0 +1,121 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// +build linux
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Buffer is sized for readdir-batch entries of an average size, but holds at least a single page
+const getdentsEntrySize = 64
+const minGetdentsBuffer = 4096
+
+// Offsets of linux_dirent64 fields returned by getdents64(2)
+const (
+	direntReclenOffset = 16
+	direntTypeOffset   = 18
+	direntNameOffset   = 19
+)
+
+// File mode types of known dirent types, anything else is resolved with lstat(2)
+var direntModes = map[byte]os.FileMode{
+	unix.DT_REG:  0,
+	unix.DT_DIR:  os.ModeDir,
+	unix.DT_LNK:  os.ModeSymlink,
+	unix.DT_FIFO: os.ModeNamedPipe,
+	unix.DT_SOCK: os.ModeSocket,
+	unix.DT_CHR:  os.ModeDevice | os.ModeCharDevice,
+	unix.DT_BLK:  os.ModeDevice,
+}
+
+// countEntries counts directory entries of a given kind in place with getdents64(2) into a reusable buffer, without
+// sorting entries or allocating their names. Types of all entries are accounted as well unless types is nil.
+func countEntries(path, kind string, hidden bool, types *entryTypes) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	counting.start(path)
+	defer counting.stop(path)
+
+	size := *readdirBatch * getdentsEntrySize
+	if size < minGetdentsBuffer {
+		size = minGetdentsBuffer
+	}
+	buf := make([]byte, size)
+	var count int
+	for {
+		n, err := unix.Getdents(int(f.Fd()), buf)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return count, os.NewSyscallError("getdents64", err)
+		}
+		if n <= 0 {
+			return count, nil
+		}
+		counting.update(path, count)
+
+		for off := 0; off < n; {
+			reclen := int(*(*uint16)(unsafe.Pointer(&buf[off+direntReclenOffset])))
+			typ, name := buf[off+direntTypeOffset], buf[off+direntNameOffset:off+reclen]
+			off += reclen
+
+			// Skip . and .. entries
+			if name[0] == '.' && (name[1] == 0 || name[1] == '.' && name[2] == 0) {
+				continue
+			}
+			if !hidden && name[0] == '.' {
+				continue
+			}
+			if kind == countAll && types == nil {
+				count++
+				continue
+			}
+
+			// Some filesystems don't fill in entry type
+			mode, ok := direntModes[typ]
+			if !ok {
+				fi, err := os.Lstat(filepath.Join(path, string(name[:bytes.IndexByte(name, 0)])))
+				if err != nil {
+					continue
+				}
+				mode = fi.Mode() & os.ModeType
+			}
+			if types != nil {
+				types.add(mode)
+			}
+			if kind == countAll || mode.IsDir() == (kind == countDirs) {
+				count++
+			}
+		}
+	}
+}
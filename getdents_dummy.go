@@ -0,0 +1,92 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// +build !linux
+
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/karrick/godirwalk"
+)
+
+// countEntries counts directory entries of a given kind, reading only names in batches when counting all of them.
+// Types of all entries are accounted as well unless types is nil.
+func countEntries(path, kind string, hidden bool, types *entryTypes) (int, error) {
+	counting.start(path)
+	defer counting.stop(path)
+
+	// Entries are scanned one at a time to keep memory bounded in huge directories
+	if kind != countAll || types != nil {
+		scanner, err := godirwalk.NewScanner(path)
+		if err != nil {
+			return 0, err
+		}
+
+		var count, scanned int
+		for scanner.Scan() {
+			if scanned++; scanned%int(*readdirBatch) == 0 {
+				counting.update(path, count)
+			}
+			de, err := scanner.Dirent()
+			if err != nil {
+				_ = scanner.Err()
+				return count, err
+			}
+			if !hidden && strings.HasPrefix(de.Name(), ".") {
+				continue
+			}
+			if types != nil {
+				types.add(de.ModeType())
+			}
+			if kind == countAll || de.IsDir() == (kind == countDirs) {
+				count++
+			}
+		}
+		return count, scanner.Err()
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var count int
+	for {
+		counting.update(path, count)
+		names, err := f.Readdirnames(int(*readdirBatch))
+		for _, name := range names {
+			if hidden || !strings.HasPrefix(name, ".") {
+				count++
+			}
+		}
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+	}
+}
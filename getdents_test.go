@@ -0,0 +1,69 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/karrick/godirwalk"
+)
+
+func TestCountEntries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "entries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i := 0; i < 20; i++ {
+		if err := os.Mkdir(filepath.Join(dir, "dir"+strconv.Itoa(i)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 10000; i++ {
+		if err := ioutil.WriteFile(filepath.Join(dir, "file"+strconv.Itoa(i)), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{".hidden", ".hiddendir"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	des, err := godirwalk.ReadDirents(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kind := range []string{countAll, countDirs, countFiles} {
+		for _, hidden := range []bool{true, false} {
+			want := countKind(des, kind, hidden)
+			if got, err := countEntries(dir, kind, hidden, nil); err != nil || got != want {
+				t.Errorf("countEntries(%v, hidden %v) = %v, %v; want %v", kind, hidden, got, err, want)
+			}
+		}
+	}
+}
@@ -5,6 +5,8 @@ require (
 	github.com/pborman/getopt/v2 v2.1.0
 	golang.org/x/net v0.0.0-20201024042810-be3efd7ff127
 	golang.org/x/sys v0.0.0-20201024232916-9f70ab9862d5
+	golang.org/x/text v0.3.3
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 go 1.13
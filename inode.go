@@ -22,45 +22,192 @@
 package main
 
 import (
-	"github.com/dkorunic/findlargedir/cerrgroup"
+	"encoding/json"
+	"errors"
+	"github.com/dkorunic/findlargedir/fsinfo"
+	"io"
 	"io/ioutil"
 	"log"
+	"math"
 	"os"
-	"runtime"
+	"path/filepath"
+	"regexp"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
 const testContent = "Death is lighter than a feather, but Duty is heavier than a mountain."
 const minRatio = 1
 const maxRatio = 128
+const staleCalibrationAge = time.Minute * 10
+const defaultReaddirBatch = 4096
+const minCalibrationFiles = 1000
+
+// staleCalibrationRe matches temporary directory names created by ioutil.TempDir() with testDirName prefix.
+var staleCalibrationRe = regexp.MustCompile("^" + regexp.QuoteMeta(testDirName) + "[0-9]+$")
+
+// A calibration holds inode measurements of a single filesystem. Filesystems without usable directory inode size
+// get their entries counted instead.
+type calibration struct {
+	Path          string        `json:"path"`
+	Device        uint64        `json:"device"`
+	FSType        string        `json:"fstype"`
+	Ratio         float64       `json:"ratio"`
+	TestFileCount int64         `json:"test_file_count"`
+	EmptySize     int64         `json:"empty_size"`
+	FullSize      int64         `json:"full_size"`
+	Count         bool          `json:"counted,omitempty"`
+	Ratios        []float64     `json:"ratios,omitempty"`
+	LowConfidence bool          `json:"low_confidence,omitempty"`
+	RSquared      float64       `json:"r_squared,omitempty"`
+	Converged     bool          `json:"converged,omitempty"`
+	Failure       string        `json:"failure,omitempty"`
+	Duration      time.Duration `json:"-"`
+}
+
+// MarshalJSON encodes calibration with its duration in seconds.
+func (c calibration) MarshalJSON() ([]byte, error) {
+	type plain calibration
+	return json.Marshal(struct {
+		plain
+		DurationSeconds float64 `json:"duration_seconds"`
+	}{plain(c), c.Duration.Seconds()})
+}
+
+// errCalibrationInterrupted stops creating test files once calibration was interrupted with salvage-calibration.
+var errCalibrationInterrupted = errors.New("calibration interrupted")
+
+// calibrationStop is set once the running calibration was interrupted with salvage-calibration.
+var calibrationStop int32
+
+// calibrationSalvaging is set while a calibration which can be salvaged on interrupt is running.
+var calibrationSalvaging int32
+
+// stopCalibration makes the running calibration stop creating test files.
+func stopCalibration() {
+	atomic.StoreInt32(&calibrationStop, 1)
+}
+
+// resetCalibrationStop lets a new calibration create test files after an earlier one was interrupted.
+func resetCalibrationStop() {
+	atomic.StoreInt32(&calibrationStop, 0)
+}
+
+// salvagingCalibration checks if interrupts are left to the calibration signal handler, to be salvaged.
+func salvagingCalibration() bool {
+	return atomic.LoadInt32(&calibrationSalvaging) == 1
+}
+
+// calibrationStopped checks if calibrations should stop creating test files.
+func calibrationStopped() bool {
+	return atomic.LoadInt32(&calibrationStop) == 1
+}
+
+// tempDirRetryDelay is how long to wait before retrying removal of a temporary directory.
+var tempDirRetryDelay = time.Second
+
+// Temporary calibration directories which still exist, removed on exit
+var tempDirMutex sync.Mutex
+var tempDirs = make(map[string]struct{})
+
+// trackTempDir registers a temporary directory to be removed if the program exits while it exists.
+func trackTempDir(dir string) {
+	tempDirMutex.Lock()
+	defer tempDirMutex.Unlock()
+	tempDirs[dir] = struct{}{}
+}
+
+// untrackTempDir unregisters an already removed temporary directory.
+func untrackTempDir(dir string) {
+	tempDirMutex.Lock()
+	defer tempDirMutex.Unlock()
+	delete(tempDirs, dir)
+}
+
+// removeTempDirs removes all temporary directories which still exist.
+func removeTempDirs() {
+	tempDirMutex.Lock()
+	defer tempDirMutex.Unlock()
+	for dir := range tempDirs {
+		log.Printf("Cleaning up temporary directory %v, please wait...", redacted(dir))
+		removeTempDir(dir)
+		delete(tempDirs, dir)
+	}
+}
+
+// removeTempDir removes a temporary directory, retrying once on failure. Directories left behind inflate estimates of
+// the directory holding them on later runs, so they are warned about and recorded in the summary.
+func removeTempDir(dir string) {
+	err := calFS.RemoveAll(dir)
+	if err == nil {
+		return
+	}
+	log.Printf("Warning: unable to remove temporary directory %v: %v. Retrying...", redacted(dir), err)
+	time.Sleep(tempDirRetryDelay)
+
+	if err := calFS.RemoveAll(dir); err != nil {
+		log.Printf("Warning: temporary directory %v was left behind: %v. Remove it manually.", redacted(dir), err)
+		summary.Leftovers = append(summary.Leftovers, dir)
+	}
+}
 
 // getInodeRatio will do a rough estimation on how much a single file occupies in a directory inode.
-func getInodeRatio(checkDir string) (ratio float64) {
+func getInodeRatio(checkDir string) (cal calibration) {
+	s := startSpan("calibration", strAttr("path", redacted(checkDir)), intAttr("test_file_count", *testFileCount))
+	start := time.Now()
+	count := *testFileCount
+	defer func() {
+		cal.Path, cal.TestFileCount, cal.Duration = checkDir, count, time.Since(start)
+		endSpan(s, floatAttr("ratio", cal.Ratio), intAttr("empty_size", cal.EmptySize))
+	}()
+
 	defer func() {
 		if r := recover(); r != nil {
-			log.Printf("Errors encountered, skipping directory scan on %q.", checkDir)
-			ratio = 0
+			log.Printf("Errors encountered, skipping directory scan on %q.", redacted(checkDir))
+			cal = calibration{Failure: calFailError}
 		}
 	}()
 
-	log.Printf("Determining inode to file count ratio on %q. Please wait, creating %v files...", checkDir,
-		*testFileCount)
+	// Calibration must not hit the very limits it is checking for
+	count, ok := calibrationFileCount(checkDir)
+	if !ok {
+		cal.Failure = calFailNoSpace
+		return
+	}
+
+	log.Printf("Determining inode to file count ratio on %q. Please wait, creating %v files...", redacted(checkDir), count)
 
 	var wg sync.WaitGroup
 
+	// Leftovers from interrupted runs inflate directory entry count
+	checkStaleCalibration(checkDir)
+
 	// Create a temporary directory in each root filesystem path and remove on exit
-	tempDir, err := ioutil.TempDir(checkDir, testDirName)
+	tempDir, err := calFS.TempDir(checkDir, testDirName)
 	if err != nil {
-		log.Print(err)
+		cal.Failure = calibrationFailure(err)
+		reportError(checkDir, err)
 		return
 	}
-	defer os.RemoveAll(tempDir)
+	trackTempDir(tempDir)
+	defer func() {
+		removeTempDir(tempDir)
+		untrackTempDir(tempDir)
+	}()
 
 	// Signal handler variables
 	signalChan := make(chan os.Signal, 1)
 	doneSignalChan := make(chan struct{}, 1)
 
-	// Signal handler goroutine: handle SIGINT and SIGTERM while creating temp files
+	// Signal handler goroutine: handle SIGINT and SIGTERM while creating temp files, the walk leaving the first one
+	// to be salvaged here
+	resetCalibrationStop()
+	if *salvageCalibrationFlag {
+		atomic.StoreInt32(&calibrationSalvaging, 1)
+		defer atomic.StoreInt32(&calibrationSalvaging, 0)
+	}
 	registerTempdirSignal(signalChan)
 	wg.Add(1)
 	go func() {
@@ -69,91 +216,348 @@ func getInodeRatio(checkDir string) (ratio float64) {
 		for {
 			select {
 			case <-signalChan:
-				log.Printf("Cleaning up temporary directory %v, please wait...", tempDir)
-				os.RemoveAll(tempDir)
+				if *salvageCalibrationFlag && !calibrationStopped() {
+					log.Printf("Interrupted, finishing calibration on %q with files created so far. Interrupt again to exit.",
+						redacted(checkDir))
+					stopCalibration()
+					continue
+				}
+				removeTempDirs()
 				log.Printf("Exiting program as requested.")
-				os.Exit(1)
+				exit(1)
 			case <-doneSignalChan:
 				return
 			}
 		}
 	}()
 
+	// Close channels and cleanup routines on any outcome
+	defer func() {
+		doneSignalChan <- struct{}{}
+		wg.Wait()
+	}()
+
 	// Get empty directory inode size
-	dirSizeEmpty, err := getDirSize(tempDir)
+	dirSizeEmpty, err := calFS.DirSize(tempDir)
 	if err != nil {
-		log.Print(err)
+		cal.Failure = calibrationFailure(err)
+		reportError(tempDir, err)
 		return
 	}
-
-	// Highly concurrent file creation routine with at most NumCPU() running routines
-	cg := cerrgroup.New(runtime.NumCPU())
-	content := []byte(testContent)
-	for i := int64(0); i < *testFileCount; i++ {
-		cg.Go(func() error {
-			t, err := ioutil.TempFile(tempDir, "")
-			if err != nil {
-				log.Print(err)
-				return err
-			}
-
-			if _, err := t.Write(content); err != nil {
-				log.Print(err)
-				return err
-			}
-
-			if err := t.Close(); err != nil {
-				log.Print(err)
-				return err
-			}
-
-			return nil
-		})
+	cal.EmptySize = dirSizeEmpty
+	if *verboseFlag {
+		log.Printf("Empty directory baseline st_size on %q is %v bytes.", redacted(checkDir), dirSizeEmpty)
 	}
 
-	// Wait for all routines to finish
-	if err = cg.Wait(); err != nil {
-		log.Print(err)
+	// Regression calibration might stop before creating all files
+	startCalibrationRamp()
+	defer stopCalibrationRamp()
+	created, slope, r2, err := createCalibrationFiles(tempDir, count, dirSizeEmpty)
+	salvaged := err == errCalibrationInterrupted
+	if salvaged {
+		created, err = salvageCalibration(tempDir)
+	}
+	if err != nil {
+		cal.Failure = calibrationFailure(err)
 		return
 	}
+	if created < count && !salvaged {
+		log.Printf("Inode ratio on %q converged after %v of %v files.", redacted(checkDir), formatCount(created),
+			formatCount(count))
+		cal.Converged = true
+	}
+	count = created
+	cal.LowConfidence = salvaged
 
 	// Get full directory inode size
-	dirSizeFull, err := getDirSize(tempDir)
+	dirSizeFull, err := calFS.DirSize(tempDir)
 	if err != nil {
-		log.Print(err)
+		cal.Failure = calibrationFailure(err)
+		reportError(tempDir, err)
+		return
+	}
+	cal.FullSize = dirSizeFull
+
+	// Some filesystems report a constant directory st_size regardless of entry count
+	if dirSizeFull == dirSizeEmpty {
+		if *countFallbackFlag {
+			log.Printf("Directory st_size does not grow on %q filesystem (%v bytes with %v files). Counting entries instead.",
+				redacted(checkDir), dirSizeFull, count)
+			cal.Count = true
+			return
+		}
+		log.Printf("Directory st_size does not grow on %q filesystem (%v bytes with %v files). Skipping folder checks, use --count-fallback to count entries instead.",
+			redacted(checkDir), dirSizeFull, count)
+		cal.Failure = calFailUnsupported
 		return
 	}
 
 	// Stat st_size value sanity check
-	if dirSizeFull < (minRatio**testFileCount) || dirSizeFull > (maxRatio**testFileCount) {
+	if dirSizeFull < (minRatio*count) || dirSizeFull > (maxRatio*count) {
 		log.Printf("Directory stat st_size structure is most likely incorrect (%v bytes used). Skipping folder checks.",
 			dirSizeFull)
+		cal.Failure = calFailUnsupported
 		return
 	}
 
 	// Calculate final file inode usage ratio
-	ratio = float64(dirSizeFull-dirSizeEmpty) / float64(*testFileCount)
+	ratio := float64(dirSizeFull-dirSizeEmpty) / float64(count)
+	if *calibrationRegressionFlag && !salvaged {
+		ratio, cal.RSquared = slope, r2
+		log.Printf("Fit directory size of %v files on %q with R² of %.4f.", count, redacted(checkDir), r2)
+	}
 
 	// Ratio sanity check
 	if ratio < minRatio || ratio > maxRatio {
 		log.Printf("Calculated ratio (%v) failed sanity checking. Skipping folder checks.", ratio)
-		ratio = 0
+		cal.Failure = calFailUnsupported
 		return
 	}
 
-	// Close channels and cleanup routines
-	doneSignalChan <- struct{}{}
-	wg.Wait()
-
-	log.Printf("Done. Approximate directory inode size to file count ratio on %q is %v.", checkDir, ratio)
+	log.Printf("Done. Approximate directory inode size to file count ratio on %q is %v.", redacted(checkDir), ratio)
+	cal.Ratio = ratio
 	return
 }
 
-// getDirSize returns inode size from Fileinfo structure.
-func getDirSize(name string) (int64, error) {
+// salvageCalibration counts test files created before calibration was interrupted, exiting if there are too few of
+// them for a usable ratio.
+func salvageCalibration(tempDir string) (int64, error) {
+	created, err := countDirEntries(tempDir)
+	if err != nil {
+		reportError(tempDir, err)
+		return 0, err
+	}
+
+	if created < minCalibrationFiles {
+		log.Printf("Only %v files were created before calibration was interrupted (at least %v needed), exiting.",
+			created, minCalibrationFiles)
+		removeTempDirs()
+		exit(1)
+	}
+	log.Printf("Calibration was interrupted, deriving a low confidence ratio from %v files created so far.", created)
+	return created, nil
+}
+
+// Calibration failure categories
+const (
+	calFailUnsupported = "unsupported"
+	calFailPermission  = "permission_denied"
+	calFailNoSpace     = "no_space"
+	calFailError       = "error"
+)
+
+// calibrationFailure categorizes an error encountered while calibrating.
+func calibrationFailure(err error) string {
+	switch {
+	case errors.Is(err, syscall.ENOSPC), errors.Is(err, syscall.EDQUOT):
+		return calFailNoSpace
+	case os.IsPermission(err), errors.Is(err, syscall.EROFS):
+		return calFailPermission
+	}
+	return calFailError
+}
+
+// noUsableCalibration checks if calibration was attempted but failed on every filesystem.
+func noUsableCalibration(cals []calibration) bool {
+	for _, cal := range cals {
+		if cal.Ratio > 0 || cal.Count {
+			return false
+		}
+	}
+	return len(cals) > 0
+}
+
+// calibrationFileCount returns number of test files to create on a filesystem, lowered to the configured cap and to
+// half of free inodes, and whether it is enough to calibrate.
+func calibrationFileCount(checkDir string) (int64, bool) {
+	count := *testFileCount
+	if *maxCalibrationFiles > 0 && count > *maxCalibrationFiles {
+		count = *maxCalibrationFiles
+	}
+	if usage, err := fsinfo.Statfs(checkDir); err == nil && usage.Files > 0 && int64(usage.FreeFiles/2) < count {
+		count = int64(usage.FreeFiles / 2)
+	}
+	if count == *testFileCount {
+		return count, true
+	}
+
+	min := int64(minCalibrationFiles)
+	if *testFileCount < min {
+		min = *testFileCount
+	}
+	if count < min {
+		log.Printf("Unable to create the minimum of %v calibration files on %q (at most %v allowed). Skipping folder checks.",
+			min, redacted(checkDir), count)
+		return count, false
+	}
+
+	log.Printf("Lowering calibration file count on %q from %v to %v, inode ratio accuracy may drop.", redacted(checkDir),
+		*testFileCount, count)
+	return count, true
+}
+
+// verifyCalibration repeats calibration in an independent temporary directory, averaging both ratios if they agree
+// within tolerance percentage and marking the filesystem low-confidence otherwise.
+func verifyCalibration(cal calibration, checkDir string, tolerance float64) calibration {
+	second := getInodeRatio(checkDir)
+	cal.Ratios = []float64{cal.Ratio, second.Ratio}
+
+	delta := ratioDelta(cal.Ratio, second.Ratio)
+	if second.Ratio <= 0 || delta > tolerance {
+		log.Printf("Inode ratios on %q disagree (%v and %v, %.2f%% apart). Low confidence, skipping folder checks.",
+			redacted(checkDir), cal.Ratio, second.Ratio, delta)
+		cal.Ratio, cal.LowConfidence, cal.Failure = 0, true, second.Failure
+		if cal.Failure == "" {
+			cal.Failure = calFailUnsupported
+		}
+		return cal
+	}
+
+	if *verboseFlag {
+		log.Printf("Inode ratios on %q agree within %.2f%% (%v and %v).", redacted(checkDir), delta, cal.Ratio, second.Ratio)
+	}
+	cal.Ratio = (cal.Ratio + second.Ratio) / 2
+	cal.Duration += second.Duration
+	return cal
+}
+
+// ratioDelta returns difference between two ratios as a percentage of the first one.
+func ratioDelta(a, b float64) float64 {
+	return math.Abs(a-b) / a * 100
+}
+
+// A calibrationFS holds filesystem operations used by calibration, replaceable in tests.
+type calibrationFS interface {
+	// TempDir creates a new temporary directory in dir.
+	TempDir(dir, pattern string) (string, error)
+	// RemoveAll removes path and any children it contains.
+	RemoveAll(path string) error
+	// DirSize returns inode size of a directory.
+	DirSize(name string) (int64, error)
+	// CreateFile creates a new file in dir with given name and content, returning its path. Empty name gets a random
+	// one.
+	CreateFile(dir, name string, content []byte) (string, error)
+}
+
+// calFS is the filesystem calibration is done on.
+var calFS calibrationFS = osFS{}
+
+// calFileName names calibration test files by their index, nil for random names. Tests set it for reproducible
+// calibration.
+var calFileName func(i int64) string
+
+// osFS implements calibrationFS on top of the operating system.
+type osFS struct{}
+
+func (osFS) TempDir(dir, pattern string) (string, error) {
+	return ioutil.TempDir(dir, pattern)
+}
+
+func (osFS) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+// DirSize returns inode size from Fileinfo structure.
+func (osFS) DirSize(name string) (int64, error) {
 	fi, err := os.Stat(name)
 	if err != nil {
 		return 0, err
 	}
 	return fi.Size(), err
 }
+
+func (osFS) CreateFile(dir, name string, content []byte) (string, error) {
+	var t *os.File
+	var err error
+	if name == "" {
+		t, err = ioutil.TempFile(dir, "")
+	} else {
+		t, err = os.OpenFile(filepath.Join(dir, name), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	}
+	if err != nil {
+		return dir, err
+	}
+
+	if _, err := t.Write(content); err != nil {
+		_ = t.Close()
+		return t.Name(), err
+	}
+	return t.Name(), t.Close()
+}
+
+// checkStaleCalibration warns about calibration directories left by interrupted runs, removing them if requested.
+func checkStaleCalibration(checkDir string) {
+	stale, err := findStaleCalibration(checkDir, time.Now().Add(-staleCalibrationAge))
+	if err != nil {
+		reportError(checkDir, err)
+		return
+	}
+	if len(stale) == 0 {
+		return
+	}
+
+	if !*cleanStaleCalibrationFlag {
+		log.Printf("Found %v stale calibration directories in %q left by interrupted runs, inflating its entry count. Use --clean-stale-calibration to remove them.",
+			len(stale), redacted(checkDir))
+		return
+	}
+
+	for _, v := range stale {
+		log.Printf("Removing stale calibration directory %q, please wait...", redacted(v))
+		if err := os.RemoveAll(v); err != nil {
+			reportError(v, err)
+		}
+	}
+}
+
+// findStaleCalibration returns calibration directories in checkDir last modified before a given time.
+func findStaleCalibration(checkDir string, before time.Time) ([]string, error) {
+	f, err := os.Open(checkDir)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	// Recently modified ones might belong to a concurrent run
+	var stale []string
+	for _, name := range names {
+		if !staleCalibrationRe.MatchString(name) {
+			continue
+		}
+
+		path := filepath.Join(checkDir, name)
+		fi, err := os.Lstat(path)
+		if err != nil || !fi.IsDir() || fi.ModTime().After(before) {
+			continue
+		}
+		stale = append(stale, path)
+	}
+
+	return stale, nil
+}
+
+// countDirEntries counts entries in a directory by reading it in batches.
+func countDirEntries(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var count int64
+	for {
+		names, err := f.Readdirnames(int(*readdirBatch))
+		count += int64(len(names))
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+	}
+}
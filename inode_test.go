@@ -0,0 +1,483 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFindStaleCalibration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stale")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	old := time.Now().Add(-time.Hour)
+	for _, name := range []string{testDirName + "123", testDirName + "789", testDirName, testDirName + "x1", "other1"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if name != testDirName+"789" {
+			if err := os.Chtimes(filepath.Join(dir, name), old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, testDirName+"456"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := findStaleCalibration(dir, time.Now().Add(-staleCalibrationAge))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, testDirName+"123")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findStaleCalibration() = %v; want %v", got, want)
+	}
+}
+
+func TestCountDirEntries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "count")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const n = defaultReaddirBatch + 10
+	for i := 0; i < n; i++ {
+		if err := ioutil.WriteFile(filepath.Join(dir, strconv.Itoa(i)), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := countDirEntries(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != n {
+		t.Errorf("countDirEntries() = %v; want %v", got, n)
+	}
+}
+
+// A fakeFS reports given directory sizes, before and after file creation, or grown by entry size per file.
+type fakeFS struct {
+	mu      sync.Mutex
+	empty   int64
+	full    int64
+	entry   int64
+	files   int64
+	tempErr error
+	rmErr   error
+	names   []string
+	created []string
+	removed []string
+}
+
+func (f *fakeFS) TempDir(dir, pattern string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.tempErr != nil {
+		return "", f.tempErr
+	}
+	name := filepath.Join(dir, pattern+strconv.Itoa(len(f.created)))
+	f.created = append(f.created, name)
+	return name, nil
+}
+
+func (f *fakeFS) RemoveAll(path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.removed = append(f.removed, path)
+	return f.rmErr
+}
+
+func (f *fakeFS) DirSize(name string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.entry > 0 {
+		return f.empty + f.entry*f.files, nil
+	}
+	if f.files == 0 {
+		return f.empty, nil
+	}
+	return f.full, nil
+}
+
+func (f *fakeFS) CreateFile(dir, name string, content []byte) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.files++
+	if name == "" {
+		name = strconv.FormatInt(f.files, 10)
+	}
+	f.names = append(f.names, name)
+	return filepath.Join(dir, name), nil
+}
+
+func TestGetInodeRatioSanityChecks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "calibration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	saved, savedCount := calFS, *testFileCount
+	defer func() { calFS, *testFileCount = saved, savedCount }()
+	*testFileCount = 100
+
+	for _, tt := range []struct {
+		name        string
+		empty, full int64
+		want        float64
+	}{
+		{"normal", 4096, 4096 + 3200, 32},
+		{"st_size below minimum", 0, minRatio*100 - 1, 0},
+		{"st_size above maximum", 0, maxRatio*100 + 1, 0},
+		{"ratio at lower bound", 0, minRatio * 100, minRatio},
+		{"ratio at upper bound", 0, maxRatio * 100, maxRatio},
+		{"ratio below lower bound", 50, minRatio*100 + 49, 0},
+	} {
+		fs := &fakeFS{empty: tt.empty, full: tt.full}
+		calFS = fs
+
+		cal := getInodeRatio(dir)
+		if cal.Ratio != tt.want {
+			t.Errorf("%v: getInodeRatio() ratio = %v; want %v", tt.name, cal.Ratio, tt.want)
+		}
+		if tt.want > 0 && cal.EmptySize != tt.empty {
+			t.Errorf("%v: getInodeRatio() empty size = %v; want %v", tt.name, cal.EmptySize, tt.empty)
+		}
+		if fs.files != *testFileCount {
+			t.Errorf("%v: getInodeRatio() created %v files; want %v", tt.name, fs.files, *testFileCount)
+		}
+		if len(fs.created) != 1 || !reflect.DeepEqual(fs.removed, fs.created) {
+			t.Errorf("%v: getInodeRatio() created %v and removed %v temporary directories; want all removed",
+				tt.name, fs.created, fs.removed)
+		}
+	}
+}
+
+func TestGetInodeRatioNoGrowth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "calibration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	saved, savedCount := calFS, *testFileCount
+	defer func() { calFS, *testFileCount, *countFallbackFlag = saved, savedCount, false }()
+	*testFileCount = 100
+
+	for _, fallback := range []bool{false, true} {
+		*countFallbackFlag = fallback
+		fs := &fakeFS{empty: 4096, full: 4096}
+		calFS = fs
+
+		cal := getInodeRatio(dir)
+		if cal.Ratio != 0 || cal.Count != fallback {
+			t.Errorf("getInodeRatio() with count fallback %v = %+v; want no ratio and counting %v", fallback, cal,
+				fallback)
+		}
+		if !reflect.DeepEqual(fs.removed, fs.created) {
+			t.Errorf("getInodeRatio() created %v and removed %v temporary directories; want all removed",
+				fs.created, fs.removed)
+		}
+	}
+}
+
+func TestVerifyCalibration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "calibration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	saved, savedCount := calFS, *testFileCount
+	defer func() { calFS, *testFileCount = saved, savedCount }()
+	*testFileCount = 100
+
+	for _, tt := range []struct {
+		first float64
+		want  float64
+		low   bool
+	}{
+		{32, 32, false},
+		{30, 31, false},
+		{40, 0, true},
+	} {
+		// Second calibration always measures a ratio of 32
+		calFS = &fakeFS{empty: 0, full: 3200}
+
+		cal := verifyCalibration(calibration{Ratio: tt.first}, dir, 10)
+		if cal.Ratio != tt.want || cal.LowConfidence != tt.low || !reflect.DeepEqual(cal.Ratios, []float64{tt.first, 32}) {
+			t.Errorf("verifyCalibration() with first ratio %v = %+v; want ratio %v and low confidence %v", tt.first,
+				cal, tt.want, tt.low)
+		}
+	}
+}
+
+func TestGetInodeRatioDeterministic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "calibration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	saved, savedCount := calFS, *testFileCount
+	defer func() { calFS, *testFileCount, calFileName = saved, savedCount, nil }()
+	*testFileCount = 100
+	calFileName = func(i int64) string { return "test" + strconv.FormatInt(i, 10) }
+
+	fs := &fakeFS{empty: 64, entry: 24}
+	calFS = fs
+
+	cal := getInodeRatio(dir)
+	if cal.Ratio != 24 || cal.EmptySize != 64 || cal.FullSize != 64+2400 {
+		t.Errorf("getInodeRatio() = %+v; want ratio 24 measured from 64 to 2464 bytes", cal)
+	}
+
+	var want []string
+	for i := 0; i < 100; i++ {
+		want = append(want, "test"+strconv.Itoa(i))
+	}
+	sort.Strings(want)
+	sort.Strings(fs.names)
+	if !reflect.DeepEqual(fs.names, want) {
+		t.Errorf("getInodeRatio() created files %v; want %v", fs.names, want)
+	}
+}
+
+func TestOSFSCreateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "calibration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	content := []byte(testContent)
+	if name, err := (osFS{}).CreateFile(dir, "fixed", content); err != nil || name != filepath.Join(dir, "fixed") {
+		t.Errorf("CreateFile() with name = %q, %v; want %q", name, err, filepath.Join(dir, "fixed"))
+	}
+	if _, err := (osFS{}).CreateFile(dir, "fixed", content); err == nil {
+		t.Errorf("CreateFile() with an existing name succeeded; want error")
+	}
+	if name, err := (osFS{}).CreateFile(dir, "", content); err != nil || filepath.Dir(name) != dir {
+		t.Errorf("CreateFile() with random name = %q, %v; want a file in %q", name, err, dir)
+	}
+}
+
+func TestCalibrationFileCount(t *testing.T) {
+	dir, err := ioutil.TempDir("", "calibration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	savedCount := *testFileCount
+	defer func() { *testFileCount, *maxCalibrationFiles = savedCount, 0 }()
+
+	for _, tt := range []struct {
+		count, max int64
+		want       int64
+		ok         bool
+	}{
+		{2000, 0, 2000, true},
+		{2000, 3000, 2000, true},
+		{2000, 1500, 1500, true},
+		{2000, 500, 500, false},
+		{100, 50, 50, false},
+	} {
+		*testFileCount, *maxCalibrationFiles = tt.count, tt.max
+		if got, ok := calibrationFileCount(dir); got != tt.want || ok != tt.ok {
+			t.Errorf("calibrationFileCount() with count %v and cap %v = %v, %v; want %v, %v", tt.count, tt.max, got,
+				ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNoUsableCalibration(t *testing.T) {
+	saved := calFS
+	defer func() { calFS = saved }()
+
+	// Every root is read-only
+	calFS = &fakeFS{tempErr: &os.PathError{Op: "mkdir", Path: "/ro", Err: syscall.EROFS}}
+	var cals []calibration
+	for _, dir := range []string{"/ro/a", "/ro/b"} {
+		cals = append(cals, getInodeRatio(dir))
+	}
+	if !noUsableCalibration(cals) {
+		t.Errorf("noUsableCalibration() with all read-only roots = false; want true")
+	}
+
+	if noUsableCalibration(nil) {
+		t.Errorf("noUsableCalibration() without calibrations = true; want false")
+	}
+	if noUsableCalibration(append(cals, calibration{Ratio: 32})) {
+		t.Errorf("noUsableCalibration() with a usable calibration = true; want false")
+	}
+	if noUsableCalibration(append(cals, calibration{Count: true})) {
+		t.Errorf("noUsableCalibration() with a counting calibration = true; want false")
+	}
+}
+
+func TestRemoveTempDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", testDirName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	kept, err := ioutil.TempDir("", testDirName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(kept)
+
+	trackTempDir(dir)
+	trackTempDir(kept)
+	untrackTempDir(kept)
+	removeTempDirs()
+
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("removeTempDirs() left tracked %v behind: %v", dir, err)
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("removeTempDirs() removed untracked %v: %v", kept, err)
+	}
+	if len(tempDirs) != 0 {
+		t.Errorf("removeTempDirs() kept %v directories tracked; want none", len(tempDirs))
+	}
+}
+
+func TestRemoveTempDir(t *testing.T) {
+	saved, savedSummary, savedDelay := calFS, summary, tempDirRetryDelay
+	defer func() { calFS, summary, tempDirRetryDelay = saved, savedSummary, savedDelay }()
+
+	fs := &fakeFS{rmErr: syscall.EPERM}
+	calFS, summary, tempDirRetryDelay = fs, Summary{}, 0
+	removeTempDir("/tmp/findlargedir1")
+	if len(fs.removed) != 2 || !reflect.DeepEqual(summary.Leftovers, []string{"/tmp/findlargedir1"}) {
+		t.Errorf("removeTempDir() tried %v times, leftovers %v; want retry and leftover recorded", len(fs.removed),
+			summary.Leftovers)
+	}
+}
+
+func BenchmarkCountEntries(b *testing.B) {
+	dir, err := ioutil.TempDir("", "bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i := 0; i < 20000; i++ {
+		if err := ioutil.WriteFile(filepath.Join(dir, strconv.Itoa(i)), nil, 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	defer func(n int64) { *readdirBatch = n }(*readdirBatch)
+	for _, n := range []int64{64, defaultReaddirBatch, 65536} {
+		*readdirBatch = n
+		b.Run(strconv.FormatInt(n, 10), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := countEntries(dir, countAll, true, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestCalibrationFailure(t *testing.T) {
+	saved := calFS
+	defer func() { calFS = saved }()
+
+	calFS = &fakeFS{tempErr: &os.PathError{Op: "mkdir", Path: "/ro", Err: syscall.EROFS}}
+	if cal := getInodeRatio("/ro"); cal.Failure != calFailPermission {
+		t.Errorf("getInodeRatio() on read-only root failure = %q; want %q", cal.Failure, calFailPermission)
+	}
+	calFS = &fakeFS{empty: 4096, full: 4096}
+	if cal := getInodeRatio(os.TempDir()); cal.Failure != calFailUnsupported {
+		t.Errorf("getInodeRatio() with constant st_size failure = %q; want %q", cal.Failure, calFailUnsupported)
+	}
+
+	for err, want := range map[error]string{
+		&os.PathError{Op: "open", Path: "/a", Err: syscall.ENOSPC}: calFailNoSpace,
+		&os.PathError{Op: "open", Path: "/a", Err: syscall.EDQUOT}: calFailNoSpace,
+		&os.PathError{Op: "open", Path: "/a", Err: syscall.EACCES}: calFailPermission,
+		&os.PathError{Op: "open", Path: "/a", Err: syscall.EIO}:    calFailError,
+	} {
+		if got := calibrationFailure(err); got != want {
+			t.Errorf("calibrationFailure(%v) = %q; want %q", err, got, want)
+		}
+	}
+}
+
+// A stoppingFS interrupts calibration with salvage-calibration once it created a number of files.
+type stoppingFS struct {
+	osFS
+	created, after int32
+}
+
+func (f *stoppingFS) CreateFile(dir, name string, content []byte) (string, error) {
+	if atomic.AddInt32(&f.created, 1) == f.after {
+		stopCalibration()
+	}
+	return f.osFS.CreateFile(dir, name, content)
+}
+
+func TestGetInodeRatioSalvaged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "salvage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	saved, savedCount := calFS, *testFileCount
+	defer func() { calFS, *testFileCount, calibrationStop = saved, savedCount, 0 }()
+	*testFileCount = 20000
+	calFS = &stoppingFS{after: 1500}
+
+	cal := getInodeRatio(dir)
+	if cal.TestFileCount < 1500 || cal.TestFileCount >= 20000 || !cal.LowConfidence {
+		t.Errorf("getInodeRatio() interrupted = %+v; want low confidence ratio from files created so far", cal)
+	}
+
+	// Next calibration is not affected by the interrupted one
+	*testFileCount, calFS = 2000, osFS{}
+	if cal := getInodeRatio(dir); cal.TestFileCount != 2000 || cal.LowConfidence || cal.Ratio <= 0 {
+		t.Errorf("getInodeRatio() after interrupted one = %+v; want a full calibration", cal)
+	}
+}
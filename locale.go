@@ -0,0 +1,65 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// localePrinter formats numbers of human readable output for a locale, nil for plain formatting.
+var localePrinter *message.Printer
+
+// setLocale selects locale for numbers of human readable output. Without one numbers are left plain, regardless of
+// environment, so output stays deterministic.
+func setLocale(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	tag, err := language.Parse(name)
+	if err != nil {
+		return fmt.Errorf("invalid locale %q: %v", name, err)
+	}
+	localePrinter = message.NewPrinter(tag)
+	return nil
+}
+
+// formatCount displays a count with digit grouping of the locale, if set.
+func formatCount(n int64) string {
+	if localePrinter == nil {
+		return strconv.FormatInt(n, 10)
+	}
+	return localePrinter.Sprintf("%d", n)
+}
+
+// formatDuration displays a duration, in seconds with decimal separator of the locale, if set.
+func formatDuration(d time.Duration) string {
+	if localePrinter == nil {
+		return d.String()
+	}
+	return localePrinter.Sprintf("%.3fs", d.Seconds())
+}
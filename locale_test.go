@@ -0,0 +1,53 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatCount(t *testing.T) {
+	defer func() { localePrinter = nil }()
+
+	if got := formatCount(1234567); got != "1234567" {
+		t.Errorf("formatCount() without locale = %q; want 1234567", got)
+	}
+	if got := formatDuration(1500 * time.Millisecond); got != "1.5s" {
+		t.Errorf("formatDuration() without locale = %q; want 1.5s", got)
+	}
+
+	if err := setLocale("not a locale!"); err == nil {
+		t.Errorf("setLocale() of an invalid locale succeeded; want error")
+	}
+	for locale, want := range map[string][2]string{"en-US": {"1,234,567", "1.500s"}, "de-DE": {"1.234.567", "1,500s"}} {
+		if err := setLocale(locale); err != nil {
+			t.Fatal(err)
+		}
+		if got := formatCount(1234567); got != want[0] {
+			t.Errorf("formatCount() for %v = %q; want %q", locale, got, want[0])
+		}
+		if got := formatDuration(1500 * time.Millisecond); got != want[1] {
+			t.Errorf("formatDuration() for %v = %q; want %q", locale, got, want[1])
+		}
+	}
+}
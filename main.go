@@ -22,13 +22,19 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"github.com/dkorunic/findlargedir/fsinfo"
 	"github.com/karrick/godirwalk"
 	"github.com/pborman/getopt/v2"
+	"io"
 	"log"
 	"math"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -38,34 +44,278 @@ const defaultAlertThreshold = 50000
 const defaultTestFileCount = 20000
 const defaultProgressTicker = time.Minute * 5
 const defaultPathnameQueueSize = 1024
+const defaultFSCacheSize = 64
+const defaultVerifyRatioTolerance = 10
+const defaultSlowThreshold = time.Second
+const exitNoCalibration = 2
 
-var alertThreshold, testFileCount *int64
-var helpFlag, accurateFlag, progressFlag, isilonFlag, cloexecFlag, oneFilesystemFlag *bool
+// Exit codes of calibration failures on the first root with strict-calibration
+var calibrationExitCodes = map[string]int{
+	calFailUnsupported: 3,
+	calFailPermission:  4,
+	calFailNoSpace:     5,
+	calFailError:       6,
+}
+
+const reservedOpenFiles = 64
+
+var fsCache = fsinfo.NewCache(defaultFSCacheSize)
+var ratioCache = make(map[uint64]calibration)
+var borrowedRatios = make(map[uint64]calibration)
+var summary = Summary{Started: time.Now()}
+var output reporter
+var outputAtomic *atomicFile
+var outputClosed bool
+var calibrationWorkers = runtime.NumCPU()
+var measureStart time.Time
+
+var alertThreshold, testFileCount, maxEstimate, sampleSubdirs, fanoutThreshold, emptyTolerance, sortWindow, topCount,
+	bottomCount, maxCalibrationFiles, roundTo, sigFigs, minEntriesForAccurate, minEstimateFilter,
+	maxEstimateFilter, maxAlerts, readdirBatch, warnThreshold, critThreshold,
+	tmpfsThreshold *int64
+var helpFlag, accurateFlag, progressFlag, isilonFlag, cloexecFlag, oneFilesystemFlag, errorsJSONFlag, versionFlag *bool
+var rootsAreFilesystemsFlag, groupByParentFlag, verboseFlag, cleanStaleCalibrationFlag, jsonPrettyFlag *bool
+var reportEmptyFlag, crossCheckFlag, reverseFlag, explainFlag, failOnInaccessibleFlag, allLocalFlag *bool
+var countFallbackFlag, descendFlaggedFlag, yesFlag, verifyRatioFlag, reportSkipsFlag, interactiveFlag,
+	overlayUnderlyingFlag, partialResultsFlag, raiseOpenFilesFlag, failFastFlag,
+	stopAtMaxAlertsFlag, countBindMountsFlag, summaryJSONOnlyFlag, calibrationRegressionFlag,
+	strictCalibrationFlag, confirmAccurateFlag, deflagShrunkFlag, noTempInTargetFlag, fsContextFlag,
+	salvageCalibrationFlag, emitCleanupFlag, compactPathsFlag, entryTypeBreakdownFlag *bool
+var pushgatewayURL, pushgatewayJob, pushgatewayUser, pushgatewayPassword, outputFormat, outputFile, sortKey *string
+var checkpointFile, cpuProfile, memProfile, traceURL, outputDir, countKindFlag, quarantineDir, redactMode, redactSalt, formatSummary,
+	rootOrder, localeName *string
+var pushgatewayTimeout, newerThan, olderThan, maxRuntimePerFS *time.Duration
+var deviceLabelList, fsTypeThresholdList, onlyDeviceList, bucketList, subtreeList, tagList *[]string
+var inaccessibleWarned, birthTimeWarned bool
+var inodePercentThreshold = new(float64)
+var countHiddenFlag = new(bool)
+var verifyRatioTolerance = new(float64)
+var slowThreshold = new(time.Duration)
+var concurrencyRamp = new(time.Duration)
+var convergenceEpsilon = new(float64)
+var inodePressureWarn = new(float64)
+var convergenceStep *int64
+var inodeTotals = make(map[uint64]uint64)
 
 func init() {
 	alertThreshold = getopt.Int64Long("threshold", 't', defaultAlertThreshold,
 		fmt.Sprintf("set file count threshold for alerting (default %v)", defaultAlertThreshold))
+	warnThreshold = getopt.Int64Long("warn-threshold", 0, 0,
+		"tag flagged directories with warning severity, replacing threshold which can't be set as well (default 0, no severities)")
+	critThreshold = getopt.Int64Long("crit-threshold", 0, 0,
+		"tag flagged directories at or above this many entries with critical severity (default 0, none)")
+	tmpfsThreshold = getopt.Int64Long("tmpfs-threshold", 0, 0,
+		"set file count threshold on memory backed filesystems such as tmpfs (default 0, same as threshold)")
 	testFileCount = getopt.Int64Long("testcount", 'c', defaultTestFileCount,
 		fmt.Sprintf("set initial file count for inode size testing phase (default %v)", defaultTestFileCount))
+	maxCalibrationFiles = getopt.Int64Long("max-calibration-files", 0, 0,
+		"cap number of files created for inode size testing, i.e. to stay under a quota (default 0, no cap)")
+	maxEstimate = getopt.Int64Long("max-file-count-estimate", 0, 0,
+		"treat estimates above this count as suspect (default 0, only inodes in use are checked)")
+	sampleSubdirs = getopt.Int64Long("sample-subdirs", 0, 0,
+		"sample this many subdirectories of large fan-out directories instead of walking them all")
+	fanoutThreshold = getopt.Int64Long("fanout-threshold", 0, defaultFanoutThreshold,
+		fmt.Sprintf("set entry count at which a directory is checked for large fan-out (default %v)", defaultFanoutThreshold))
+	deviceLabelList = getopt.ListLong("device-labels", 0,
+		"override labels of devices in output, as comma separated device=label pairs", "list")
+	tagList = getopt.ListLong("tag", 0,
+		"attach key=value tags to every result and the summary in machine readable output (repeatable or comma separated)",
+		"key=value")
+	onlyDeviceList = getopt.ListLong("only-device", 0,
+		"limit the walk to filesystems mounted at these mount points (repeatable or comma separated)", "mountpoint")
+	subtreeList = getopt.ListLong("subtree", 0,
+		"walk only these directories within roots, calibrating their filesystems as usual (repeatable or comma separated)",
+		"path")
+	bucketList = getopt.ListLong("buckets", 0,
+		"summarize directories in entry count buckets split at these comma separated boundaries", "list")
+	fsTypeThresholdList = getopt.ListLong("threshold-by-fstype", 0,
+		"override file count threshold per filesystem type, as comma separated fstype=count pairs", "list")
+	redactMode = getopt.EnumLong("redact", 0, []string{redactNone, redactHash, redactMask}, redactNone,
+		"redact paths in output, log lines and pushed metrics by hashing components or masking all but the top-level one (default none)", "mode")
+	redactSalt = getopt.StringLong("redact-salt", 0, "", "salt path hashes of --redact hash", "salt")
+	rootOrder = getopt.EnumLong("root-order", 0, []string{rootOrderNone, rootOrderSize, rootOrderInodes}, rootOrderNone,
+		"scan likely large roots first by root directory size or used inode share (default none)", "order")
+	sortKey = getopt.EnumLong("sort", 0, []string{sortPath, sortEstimate, sortRatio, sortDevice, sortNone},
+		sortEstimate, "sort results of each root by path, estimate, ratio, device or none (default estimate)", "key")
+	reverseFlag = getopt.BoolLong("reverse", 0, "reverse sort order")
+	roundTo = getopt.Int64Long("round-to", 0, 0,
+		"display estimates rounded to nearest multiple of this value instead of magnitude in human output")
+	sigFigs = getopt.Int64Long("sig-figs", 0, 0,
+		"display estimates rounded to this many significant figures instead of magnitude in human output")
+	topCount = getopt.Int64Long("top", 0, 0, "summarize this many largest flagged directories of the whole run")
+	bottomCount = getopt.Int64Long("bottom", 0, 0,
+		"summarize this many smallest flagged directories of the whole run, to judge the threshold")
+	sortWindow = getopt.Int64Long("sort-window", 0, 0,
+		"stream results sorted only within a sliding buffer of this many results (default 0, sort whole roots)")
+	*countHiddenFlag = true
+	getopt.FlagLong(countHiddenFlag, "count-hidden-in-estimate", 0,
+		"count hidden entries in accurate mode as the estimate always includes them (default true, =false to skip)")
+	verifyRatioFlag = getopt.BoolLong("verify-ratio", 0,
+		"calibrate twice and skip filesystems where ratios disagree beyond tolerance")
+	*verifyRatioTolerance = defaultVerifyRatioTolerance
+	getopt.FlagLong(verifyRatioTolerance, "verify-ratio-tolerance", 0,
+		fmt.Sprintf("set tolerated difference of verified ratios in percent (default %v)", defaultVerifyRatioTolerance),
+		"percent")
+	getopt.FlagLong(inodePressureWarn, "inode-pressure-warn", 0,
+		"warn when flagged directories on a filesystem hold this percentage of its free inodes", "percent")
+	getopt.FlagLong(inodePercentThreshold, "inode-percent-threshold", 0,
+		"also flag directories using at least this percentage of filesystem inode capacity", "percent")
+	traceURL = getopt.StringLong("trace", 0, "", "export OpenTelemetry spans to an OTLP/HTTP collector", "url")
+	cpuProfile = getopt.StringLong("cpuprofile", 0, "", "write CPU profile to a file", "path")
+	memProfile = getopt.StringLong("memprofile", 0, "", "write memory profile to a file", "path")
+	maxRuntimePerFS = getopt.DurationLong("max-runtime-per-fs", 0, 0,
+		"cap walk time spent on any single filesystem, scanning it only partially when exceeded", "duration")
+	newerThan = getopt.DurationLong("newer-than", 0, 0,
+		"report only directories created (or modified) within a given duration", "duration")
+	olderThan = getopt.DurationLong("older-than", 0, 0,
+		"report only directories created (or modified) more than a given duration ago", "duration")
+	minEstimateFilter = getopt.Int64Long("min-estimate", 0, 0,
+		"report only flagged directories with estimates of at least this many entries")
+	maxEstimateFilter = getopt.Int64Long("max-estimate", 0, 0,
+		"report only flagged directories with estimates of at most this many entries")
+	countKindFlag = getopt.EnumLong("count-kind", 0, []string{countFiles, countDirs, countAll}, countAll,
+		"set what accurate mode counts: files, dirs or all entries (default all)", "kind")
+	outputDir = getopt.StringLong("output-dir", 0, "",
+		"write results of each filesystem to a separate file in a directory", "path")
+	checkpointFile = getopt.StringLong("checkpoint", 0, "",
+		"record completed top-level directories in a file to resume interrupted scans", "path")
+	raiseOpenFilesFlag = getopt.BoolLong("raise-open-files-limit", 0,
+		"raise soft limit on open files up to the hard limit before scanning")
+	readdirBatch = getopt.Int64Long("readdir-batch", 0, defaultReaddirBatch,
+		"read this many directory entries per syscall when counting entries")
+	maxAlerts = getopt.Int64Long("max-alerts", 0, 0, "stop reporting flagged directories once this many were reported")
+	stopAtMaxAlertsFlag = getopt.BoolLong("stop-at-max-alerts", 0, "stop scanning once --max-alerts were reported")
+	strictCalibrationFlag = getopt.BoolLong("strict-calibration", 0,
+		"exit with an error code telling why calibration of the first root failed")
+	compactPathsFlag = getopt.BoolLong("compact-paths", 0,
+		"display paths relative to the longest common directory of results of each root")
+	emitCleanupFlag = getopt.BoolLong("emit-cleanup-commands", 0,
+		"suggest a shell command removing entries of each large directory, without running it")
+	salvageCalibrationFlag = getopt.BoolLong("salvage-calibration", 0,
+		"on first interrupt derive a low confidence ratio from test files created so far and keep scanning")
+	fsContextFlag = getopt.BoolLong("fs-context", 0,
+		"report mount point and free space of the filesystem holding each flagged directory")
+	noTempInTargetFlag = getopt.BoolLong("no-temp-in-target", 0,
+		"calibrate outside of scan roots, in the closest writable parent directory on the same filesystem")
+	calibrationRegressionFlag = getopt.BoolLong("calibration-regression", 0,
+		"calibrate in growing batches, stopping early once the fit inode ratio is stable")
+	getopt.FlagLong(convergenceEpsilon, "convergence-epsilon", 0,
+		"stop calibration early once the inode ratio changes by less than this percentage between checkpoints",
+		"percent")
+	convergenceStep = getopt.Int64Long("convergence-step", 0, defaultConvergenceStep,
+		fmt.Sprintf("check inode ratio convergence after this many test files (default %v)", defaultConvergenceStep))
+	summaryJSONOnlyFlag = getopt.BoolLong("summary-json-only", 0,
+		"write only the JSON summary object to stdout, without per-directory records")
+	countBindMountsFlag = getopt.BoolLong("count-bind-mounts", 0,
+		"measure directories reached through several bind mounts at every path instead of the first one")
+	failFastFlag = getopt.BoolLong("fail-fast", 0, "abort scan with an error on the first directory or calibration error")
+	partialResultsFlag = getopt.BoolLong("partial-results-on-error", 0,
+		"write out results gathered so far when the scan ends early on an error, marked as incomplete")
+	overlayUnderlyingFlag = getopt.BoolLong("overlay-underlying", 0,
+		"scan upper and lower directories underlying roots on overlay filesystems instead of the merged view")
+	reportSkipsFlag = getopt.BoolLong("report-skips", 0, "report directories skipped by the walk with a skip reason")
+	descendFlaggedFlag = getopt.BoolLong("descend-flagged", 0,
+		"keep descending into flagged directories to report large children as well")
+	countFallbackFlag = getopt.BoolLong("count-fallback", 0,
+		"count entries on filesystems where directory st_size does not grow")
+	allLocalFlag = getopt.BoolLong("all-local-filesystems", 0,
+		"scan mount points of all local filesystems, implies onefilesystem mode")
+	failOnInaccessibleFlag = getopt.BoolLong("fail-on-inaccessible", 0,
+		"exit with error when any directory was inaccessible")
+	explainFlag = getopt.BoolLong("explain", 0, "display planned calibration without creating any files")
+	crossCheckFlag = getopt.BoolLong("cross-check", 0,
+		"compare summed estimates on each filesystem against its used inode count")
+	reportEmptyFlag = getopt.BoolLong("report-empty", 0, "report every scanned directory regardless of threshold")
+	formatSummary = getopt.StringLong("format-summary", 0, "",
+		"write a final summary line formatted with this Go template (i.e. {{.Flagged}} large in {{.Elapsed}})",
+		"template")
+	localeName = getopt.StringLong("locale", 0, "",
+		"group digits of counts and format durations of human output for a locale, i.e. de-DE (default none)", "locale")
+	jsonPrettyFlag = getopt.BoolLong("json-pretty", 0, "indent json output for human inspection")
+	emptyTolerance = getopt.Int64Long("empty-tolerance", 0, 0,
+		"skip directories with st_size within this many bytes of an empty directory")
 	helpFlag = getopt.BoolLong("help", 'h', "display help")
+	versionFlag = getopt.BoolLong("version", 'V', "display version and build information")
+	verboseFlag = getopt.BoolLong("verbose", 'v', "display verbose output")
+	getopt.FlagLong(concurrencyRamp, "concurrency-ramp", 0,
+		"ramp up calibration workers from 1 to all over this duration to smooth out the initial IO spike", "duration")
+	*slowThreshold = defaultSlowThreshold
+	getopt.FlagLong(slowThreshold, "slow-threshold", 0,
+		"warn about directories taking at least this long to measure in verbose mode (default 1s)", "duration")
 	accurateFlag = getopt.BoolLong("accurate", 'a', "full accuracy when checking large directories")
+	deflagShrunkFlag = getopt.BoolLong("deflag-shrunk", 0,
+		"stop counting large directories that shrank below threshold by the time accurate mode counted them")
+	confirmAccurateFlag = getopt.BoolLong("confirm-with-accurate", 0,
+		"count large directories exactly before reporting them, dropping false positives")
+	minEntriesForAccurate = getopt.Int64Long("min-entries-for-accurate", 0, 0,
+		"count large directories with estimates below this many entries exactly instead of estimating")
+	entryTypeBreakdownFlag = getopt.BoolLong("entry-type-breakdown", 0,
+		"break down entries of directories counted in accurate mode by type (files, directories, symlinks, other)")
+	quarantineDir = getopt.StringLong("quarantine", 0, "",
+		"move large directories verified in accurate mode into this directory (dry run without --yes)", "path")
+	yesFlag = getopt.BoolLong("yes", 0, "confirm moving directories with --quarantine")
+	interactiveFlag = getopt.BoolLong("interactive", 0, "confirm moving each directory with --quarantine")
 	progressFlag = getopt.BoolLong("progress", 'p', "display progress status every 5 minutes")
 	isilonFlag = getopt.BoolLong("isilon", '7', "enable support for EMC Isilon OneFS 7.x")
 	cloexecFlag = getopt.BoolLong("cloexec", 'x', "disable open O_CLOEXEC for really ancient Unix systems")
 	oneFilesystemFlag = getopt.BoolLong("onefilesystem", 'o', "never cross filesystem boundaries")
+	errorsJSONFlag = getopt.BoolLong("errors-json", 'j', "report per-path errors as JSON records on stderr")
+	outputFormat = getopt.EnumLong("output", 'O', []string{outputHuman, outputJSON, outputNDJSON, outputCSV,
+		outputYAML}, outputHuman, "set output format: human, json, ndjson, csv or yaml (default human)", "format")
+	outputFile = getopt.StringLong("output-file", 'f', "",
+		"write output atomically to this file, - for stdout (default stderr for human, stdout otherwise)", "path")
+	groupByParentFlag = getopt.BoolLong("group-by-parent", 'g', "aggregate large directories under their common parent")
+	pushgatewayURL = getopt.StringLong("pushgateway", 0, "", "push scan metrics to Prometheus Pushgateway at this URL", "url")
+	pushgatewayJob = getopt.StringLong("job", 0, defaultPushgatewayJob,
+		fmt.Sprintf("set Pushgateway job label (default %v)", defaultPushgatewayJob))
+	pushgatewayUser = getopt.StringLong("pushgateway-user", 0, "", "set Pushgateway basic auth username")
+	pushgatewayPassword = getopt.StringLong("pushgateway-password", 0, "", "set Pushgateway basic auth password")
+	pushgatewayTimeout = getopt.DurationLong("pushgateway-timeout", 0, defaultPushgatewayTimeout,
+		fmt.Sprintf("set Pushgateway push timeout (default %v)", defaultPushgatewayTimeout))
+	cleanStaleCalibrationFlag = getopt.BoolLong("clean-stale-calibration", 0,
+		"remove calibration directories left by interrupted runs")
+	rootsAreFilesystemsFlag = getopt.BoolLong("roots-are-filesystems", 0,
+		"assume each root is a separate filesystem and calibrate it exactly once")
 }
 
 func main() {
 	getopt.Parse()
 	args := getopt.Args()
 
+	if *versionFlag {
+		fmt.Println(getBuildInfo())
+		os.Exit(0)
+	}
+
+	// Every local filesystem is a root on its own, nested mounts get scanned separately
+	if *allLocalFlag {
+		mounts, err := localMountpoints()
+		if err != nil {
+			log.Printf("Unable to list local filesystems: %v", err)
+			exit(1)
+		}
+		args = append(args, mounts...)
+		*oneFilesystemFlag = true
+	}
+
 	if *helpFlag || len(args) < 1 {
 		getopt.PrintUsage(os.Stderr)
 		os.Exit(0)
 	}
 
+	startProfiling()
+	defer stopProfiling()
+
+	if err := setLocale(*localeName); err != nil {
+		log.Print(err)
+		exit(1)
+	}
+	if !setSeverityTiers(getopt.IsSet("threshold")) {
+		exit(1)
+	}
+
 	log.Printf("Note: program will attempt to identify directories larger than %v entries. Make sure you have r/w privileges.",
-		*alertThreshold)
+		formatCount(*alertThreshold))
 
 	// If Unix system doesn't support open O_CLOEXEC, try monkey patching syscall.Open
 	// This will work only on FreeBSD and derivatives
@@ -79,27 +329,224 @@ func main() {
 		patchSyscallLstat()
 	}
 
+	if *quarantineDir != "" && !*accurateFlag {
+		log.Printf("Quarantine requires large directories to be verified with accurate mode (-a).")
+		exit(1)
+	}
+	if *entryTypeBreakdownFlag && !*accurateFlag {
+		log.Printf("Entry type breakdown requires large directories to be counted with accurate mode (-a).")
+		exit(1)
+	}
+	if *interactiveFlag {
+		if *quarantineDir == "" || !isTerminal(os.Stdin) {
+			log.Printf("Interactive mode requires --quarantine and a terminal to read answers from.")
+			exit(1)
+		}
+		quarantinePrompt = newPrompt(os.Stdin, os.Stderr)
+	}
+
+	if *calibrationRegressionFlag && *convergenceEpsilon > 0 {
+		log.Printf("Calibration regression and convergence epsilon stop calibration early in different ways, use only one of them.")
+		exit(1)
+	}
+
+	if *summaryJSONOnlyFlag && *outputDir != "" {
+		log.Printf("Summary only JSON output can't be split into per-filesystem files with --output-dir.")
+		exit(1)
+	}
+	if *readdirBatch < 1 {
+		log.Printf("Readdir batch size must be at least 1.")
+		exit(1)
+	}
+
+	limitWorkers()
+
+	if !*countBindMountsFlag {
+		binds = loadBindIndex()
+	}
+
+	// Calibration leftovers are removed however the program exits, or panics
+	atExit(removeTempDirs)
+	defer removeTempDirs()
+
+	if err := parseDeviceLabels(*deviceLabelList); err != nil {
+		log.Print(err)
+		exit(1)
+	}
+	if err := parseTags(*tagList); err != nil {
+		log.Print(err)
+		exit(1)
+	}
+	summary.Tags = tags
+	if err := parseFSTypeThresholds(*fsTypeThresholdList); err != nil {
+		log.Print(err)
+		exit(1)
+	}
+	if err := parseOnlyDevices(*onlyDeviceList); err != nil {
+		log.Print(err)
+		exit(1)
+	}
+	buckets, err := parseBuckets(*bucketList)
+	if err != nil {
+		log.Print(err)
+		exit(1)
+	}
+	summary.Buckets = buckets
+	if *formatSummary != "" {
+		t, err := parseSummaryTemplate(*formatSummary)
+		if err != nil {
+			log.Print(err)
+			exit(1)
+		}
+		summaryTemplate = t
+	}
+
+	// Only display what would be done
+	if *explainFlag {
+		for i := range args {
+			explainRoot(filepath.Clean(args[i]))
+		}
+		return
+	}
+
+	startTracing()
+	setupOutput()
+
+	// Runs before output gets aborted on exit
+	if *partialResultsFlag {
+		atExit(writePartialResults)
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Unrecoverable error: %v", r)
+				exit(1)
+			}
+		}()
+	}
+
+	if *maxRuntimePerFS > 0 {
+		budget = newFSBudget(*maxRuntimePerFS)
+	}
+
+	// Progress is saved when exiting prematurely as well
+	if *checkpointFile != "" {
+		checkpoint = loadCheckpoint(*checkpointFile)
+		atExit(checkpoint.save)
+	}
+
 	for i := range args {
-		processDirectory(filepath.Clean(args[i]))
+		if *overlayUnderlyingFlag {
+			scanRoots = append(scanRoots, overlayRoots(filepath.Clean(args[i]))...)
+			continue
+		}
+		scanRoots = append(scanRoots, filepath.Clean(args[i]))
+	}
+	scanRoots = dedupRoots(scanRoots)
+	if err := parseSubtrees(*subtreeList, scanRoots); err != nil {
+		log.Print(err)
+		exit(1)
+	}
+	summary.Parameters = newRunParameters()
+	summary.ParamsHash = parametersHash(*summary.Parameters)
+	scanRoots = orderRoots(scanRoots, *rootOrder)
+	for _, root := range scanRoots {
+		if alertBudgetExhausted() {
+			log.Printf("Stopping scan as %v flagged directories have been reported.", *maxAlerts)
+			break
+		}
+		processDirectory(root)
+		summary.Roots++
 	}
+	if summary.AlertsCapped {
+		log.Printf("Reporting was capped at %v flagged directories, %v more were not reported.", *maxAlerts,
+			summary.Unreported)
+	}
+	summary.addPressure(pressureSums.finish())
+	summary.Elapsed = time.Since(summary.Started)
+	summary.CPU, summary.PeakMemory = cpuTime(), peakMemory()
+
+	if err := closeOutput(); err != nil {
+		reportError(*outputFile, err)
+		exit(1)
+	}
+
+	if summaryTemplate != nil {
+		if err := writeSummaryLine(summaryWriter(), summaryTemplate, redactedSummary(&summary)); err != nil {
+			log.Printf("Unable to write summary line: %v", err)
+		}
+	}
+
+	// Scan is complete, next run should start from scratch
+	if checkpoint != nil {
+		checkpoint.remove()
+	}
+
+	if *pushgatewayURL != "" {
+		pushMetrics(&summary)
+	}
+
+	finishTracing()
+
+	// Failing every calibration would otherwise look like nothing was found
+	if !countingMode && noUsableCalibration(summary.Calibrations) {
+		log.Printf("Exiting with error as no filesystem could be calibrated. Make sure roots are writable, or use --count-fallback to count entries where directory st_size is not usable.")
+		exit(exitNoCalibration)
+	}
+
+	if *failOnInaccessibleFlag && summary.Inaccessible > 0 {
+		log.Printf("Exiting with error as %v directories were inaccessible.", summary.Inaccessible)
+		exit(1)
+	}
+
+	exitSeverity(summary.Severity)
 }
 
 // processDirectory will process individual root filesystem/folder path and identify blackhole directory offenders.
 func processDirectory(rootPath string) {
-	// Establish file to directory inode ratio
-	ratio := getInodeRatio(rootPath)
-	if ratio <= 0 {
-		log.Printf("Unable to calculate inode to file count ratio on %q. Skipping.", rootPath)
-		return
+	startRootSpan(rootPath)
+	defer endRootSpan()
+
+	if budget != nil {
+		budget.reset()
 	}
 
 	// Save root stat info for later use
 	rootStat, err := os.Lstat(rootPath)
 	if err != nil {
-		log.Print(err)
+		reportError(rootPath, err)
+		return
+	}
+
+	// Roots off listed devices are only walked through on the way to listed mount points
+	rootAllowed := deviceAllowed(getDev(rootStat))
+	if !rootAllowed && (*oneFilesystemFlag || *rootsAreFilesystemsFlag || !leadsToDevice(rootPath)) {
+		log.Printf("Root %q does not lead to any of the only device filesystems. Skipping.", redacted(rootPath))
+		reportSkip(rootPath, skipDeviceFilter, rootStat)
+		output.Flush()
 		return
 	}
 
+	if !inSubtree(rootPath) && !leadsToSubtree(rootPath) {
+		log.Printf("Root %q holds none of the subtrees. Skipping.", redacted(rootPath))
+		output.Flush()
+		return
+	}
+
+	// Establish file to directory inode ratio, unless entries get counted
+	var rootCal calibration
+	if !countingMode && rootAllowed {
+		rootCal = getCalibration(rootStat, rootPath)
+		if rootCal.Ratio <= 0 && !rootCal.Count {
+			log.Printf("Unable to calculate inode to file count ratio on %q. Skipping.", redacted(rootPath))
+			reportSkip(rootPath, skipNoRatio, rootStat)
+			if *strictCalibrationFlag && summary.Roots == 0 {
+				exitCalibration(rootCal)
+			}
+			failFast()
+			output.Flush()
+			return
+		}
+	}
+
 	// Common Goroutine variables
 	var wg sync.WaitGroup
 	var lastPathname *string
@@ -122,10 +569,14 @@ func processDirectory(rootPath string) {
 				// SIGUSR1, SIGUSR2: display progress update and resume
 				printPath(lastPathname)
 			case <-signalTermChan:
+				// Running calibration finishes with files created so far instead
+				if salvagingCalibration() {
+					continue
+				}
 				// SIGTERM: display progress update and exit with error
 				printPath(lastPathname)
 				log.Printf("Exiting program as requested.")
-				os.Exit(1)
+				exit(1)
 			case <-doneSignalChan:
 				return
 			}
@@ -158,83 +609,744 @@ func processDirectory(rootPath string) {
 	}
 
 	// Deep-dive directory counting goroutine variables
-	accurateChan := make(chan string, defaultPathnameQueueSize)
+	accurateChan := make(chan accurateCheck, defaultPathnameQueueSize)
+	var verified []string
+	var shrunk []shrunkDir
+	var breakdowns []entryBreakdown
 
-	// Async large-directory accurate counting
+	// Async large-directory accurate counting, which might outlast the walk with a single huge directory
+	var accurateWg sync.WaitGroup
 	if *accurateFlag {
-		wg.Add(1)
+		accurateWg.Add(1)
 		go func() {
-			defer wg.Done()
+			defer accurateWg.Done()
 
 			for v := range accurateChan {
-				deChildren, err := godirwalk.ReadDirents(v, nil)
+				ok, s, b, err := verifyEntries(v)
 				if err != nil {
-					log.Print(err)
+					reportError(v.path, err)
 					continue
 				}
-
-				log.Printf("Correct enumeration: directory %q has exactly %v entries.", v, len(deChildren))
+				if ok {
+					verified = append(verified, v.path)
+				}
+				if s != nil {
+					shrunk = append(shrunk, *s)
+				}
+				if b != nil {
+					breakdowns = append(breakdowns, *b)
+				}
 			}
 		}()
 	}
 
-	var offenderTotal, countFromStat int64
+	var offenderTotal, suspectTotal, fanoutTotal, vanishedTotal, inaccessibleTotal, countFromStat int64
+	rootSums = newCrossCheckSums()
 
-	// Fast concurrent directory walker: won't follow symlinks and won't sort entries
-	_ = godirwalk.Walk(rootPath, &godirwalk.Options{
-		Unsorted:            true,
-		FollowSymbolicLinks: false,
-		// Default callback will process only directory entries
-		Callback: func(osPathname string, de *godirwalk.Dirent) error {
-			// Process only if entry is directory
-			if de.IsDir() {
-				lastPathname = &osPathname
-				fi, err := os.Stat(osPathname)
+	// Default callback will process only directory entries
+	walkDir := func(osPathname string, de *godirwalk.Dirent) error {
+		// Stop walking altogether once enough alerts have been reported
+		if alertBudgetExhausted() {
+			return errAlertBudget
+		}
+
+		// Process only if entry is directory
+		if de.IsDir() {
+			lastPathname = &osPathname
+			fi, vanished, err := statDir(osPathname)
+			if err != nil {
+				return err
+			}
+			if vanished {
+				vanishedTotal++
+				reportSkip(osPathname, skipVanished, nil)
+				return godirwalk.SkipThis
+			}
+
+			// Directories holding subtrees the walk is limited to are only passed through
+			if !inSubtree(osPathname) {
+				if leadsToSubtree(osPathname) {
+					return nil
+				}
+				return godirwalk.SkipThis
+			}
+			summary.Scanned++
+
+			// Filesystems exceeding their time budget are left partially scanned
+			if budget != nil && budget.charge(getDev(fi), osPathname, time.Now()) {
+				reportSkip(osPathname, skipTimeBudget, fi)
+				return godirwalk.SkipThis
+			}
+
+			// Directories off listed devices are only passed through on the way to listed mount points
+			if !deviceAllowed(getDev(fi)) {
+				if leadsToDevice(osPathname) {
+					return nil
+				}
+				reportSkip(osPathname, skipDeviceFilter, fi)
+				return godirwalk.SkipThis
+			}
+
+			// The same directory reached again through another bind mount would be measured twice
+			if binds != nil {
+				if first, ok := binds.duplicateOf(getDev(fi), osPathname); ok {
+					reportBindDuplicate(osPathname, first, fi)
+					return godirwalk.SkipThis
+				}
+			}
+
+			// Check if we are crossing filesystem boundaries, unless roots are known to be filesystems
+			cal := rootCal
+			if (*oneFilesystemFlag || !*rootsAreFilesystemsFlag) && !isSameFilesystem(rootStat, fi) {
+				if *oneFilesystemFlag {
+					log.Printf("Directory %q is a mount point (%v), skipping further checks.", redacted(osPathname),
+						fsDescription(fi, osPathname))
+					reportSkip(osPathname, skipMountPoint, fi)
+					return godirwalk.SkipThis
+				}
+
+				// Different filesystem needs its own ratio
+				cal = nestedCalibration(fi, osPathname, rootStat, rootCal)
+				if cal.Ratio <= 0 && !cal.Count {
+					log.Printf("Unable to calculate inode to file count ratio on %q. Skipping.", redacted(osPathname))
+					reportSkip(osPathname, skipNoRatio, fi)
+					failFast()
+					return godirwalk.SkipThis
+				}
+			}
+
+			// Without a usable inode size, count entries and report them as exact
+			if countingMode || cal.Count {
+				countFromStat, err = countDirEntries(osPathname)
 				if err != nil {
 					return err
 				}
+				if countFromStat >= thresholdFor(getDev(fi), osPathname) ||
+					exceedsInodePercent(osPathname, fi, countFromStat) {
+					if addResult(Result{Path: osPathname, Kind: resultLarge, Estimate: countFromStat, Counted: true},
+						fi) {
+						offenderTotal++
+					}
+					return flaggedAction()
+				}
+				reportScanned(osPathname, countFromStat, 0, fi)
+				return nil
+			}
+
+			// Directories not larger than an empty one are obviously small
+			if fi.Size() <= cal.EmptySize+*emptyTolerance {
+				reportScanned(osPathname, 0, cal.Ratio, fi)
+				return nil
+			}
 
-				// Check if we are crossing filesystem boundaries
-				if *oneFilesystemFlag && !isSameFilesystem(rootStat, fi) {
-					log.Printf("Directory %q is a mount point, skipping further checks.", osPathname)
-					return fmt.Errorf("directory %q is a mount point", osPathname)
+			// Continue with approximate checking
+			countFromStat = int64(float64(fi.Size()) / cal.Ratio)
+			if countFromStat >= thresholdFor(getDev(fi), osPathname) ||
+				exceedsInodePercent(osPathname, fi, countFromStat) {
+				// Sanity check against impossible estimates caused by a bogus ratio
+				if limit, ok := isPlausibleEstimate(osPathname, countFromStat); !ok {
+					if addResult(Result{Path: osPathname, Kind: resultSuspect, Estimate: countFromStat, Limit: limit,
+						Ratio: cal.Ratio}, fi) {
+						suspectTotal++
+
+						// Accurate counting will tell the real story
+						if *accurateFlag {
+							accurateChan <- accurateCheck{path: osPathname, kind: resultSuspect, estimate: countFromStat}
+						}
+					}
+					return flaggedAction()
 				}
 
-				// Continue with approximate checking
-				countFromStat = int64(float64(fi.Size()) / ratio)
-				if countFromStat >= int64(*alertThreshold) {
-					log.Printf("Directory %q is possibly a large directory with %v entries.", osPathname,
-						humanPrint(countFromStat))
+				// Small flagged directories are cheap to count exactly, alerts may need to be confirmed as well
+				count, counted := confirmEstimate(osPathname, countFromStat)
+				if counted && count < thresholdFor(getDev(fi), osPathname) &&
+					!exceedsInodePercent(osPathname, fi, count) {
+					if *verboseFlag {
+						log.Printf("Directory %q has %v counted entries, estimate of %v was a false positive.",
+							redacted(osPathname), count, countFromStat)
+					}
+					reportScanned(osPathname, count, cal.Ratio, fi)
+					return nil
+				}
+
+				r := Result{Path: osPathname, Kind: resultLarge, Estimate: count, Counted: counted, Ratio: cal.Ratio}
+				if counted {
+					r.Estimated = countFromStat
+				}
+				if addResult(r, fi) {
 					offenderTotal++
 
 					// If necessary deep-dive the directory and get accurate file count
 					if *accurateFlag {
-						accurateChan <- osPathname
+						accurateChan <- accurateCheck{path: osPathname, kind: resultLarge, estimate: count}
+					}
+				}
+				return flaggedAction()
+			}
+
+			// Huge fan-out directories get sampled instead of walked
+			if *sampleSubdirs > 0 && countFromStat >= *fanoutThreshold {
+				f, ok, err := sampleFanout(osPathname, cal.Ratio, int(*sampleSubdirs), int(*fanoutThreshold))
+				if err != nil {
+					return err
+				}
+				if ok {
+					if addResult(Result{Path: osPathname, Kind: resultFanout, Estimate: f.Estimate,
+						Subdirs: f.Subdirs, Sampled: f.Sampled, Ratio: cal.Ratio}, fi) {
+						fanoutTotal++
 					}
-					return fmt.Errorf("directory %q is too large to process", osPathname)
+					return godirwalk.SkipThis
 				}
 			}
+
+			reportScanned(osPathname, countFromStat, cal.Ratio, fi)
+		}
+		return nil
+	}
+
+	// Measurement of each directory is timed in verbose mode to find slow spots
+	timedWalkDir := func(osPathname string, de *godirwalk.Dirent) error {
+		if !de.IsDir() {
+			return walkDir(osPathname, de)
+		}
+		startMeasureSpan(osPathname)
+		defer endMeasureSpan()
+		if !*verboseFlag {
+			return walkDir(osPathname, de)
+		}
+
+		measureStart = time.Now()
+		err := walkDir(osPathname, de)
+		reportMeasure(osPathname, time.Since(measureStart))
+		measureStart = time.Time{}
+		return err
+	}
+
+	// Results of top-level directories completed by an interrupted run are replayed instead of walking them again
+	if checkpoint != nil {
+		completed := checkpoint.begin(rootPath)
+		if len(completed) > 0 {
+			log.Printf("Resuming scan of %q from checkpoint, skipping %v completed directories.", redacted(rootPath),
+				len(completed))
+		}
+		for _, name := range sortedNames(completed) {
+			for _, r := range completed[name] {
+				switch r.Kind {
+				case resultLarge:
+					offenderTotal++
+				case resultSuspect:
+					suspectTotal++
+				case resultFanout:
+					fanoutTotal++
+				}
+				reportResult(r)
+			}
+		}
+	}
+
+	// Fast concurrent directory walker: won't follow symlinks and won't sort entries
+	_ = godirwalk.Walk(rootPath, &godirwalk.Options{
+		Unsorted:            true,
+		FollowSymbolicLinks: false,
+		// Top-level directories are tracked for checkpointing, they are complete once skipped or walked through
+		Callback: func(osPathname string, de *godirwalk.Dirent) error {
+			if checkpoint == nil || !de.IsDir() || !isTopLevel(rootPath, osPathname) {
+				return timedWalkDir(osPathname, de)
+			}
+
+			name := filepath.Base(osPathname)
+			if checkpoint.isCompleted(name) {
+				return godirwalk.SkipThis
+			}
+			checkpoint.start()
+
+			err := timedWalkDir(osPathname, de)
+			if err == godirwalk.SkipThis {
+				checkpoint.complete(name)
+			}
+			return err
+		},
+		PostChildrenCallback: func(osPathname string, de *godirwalk.Dirent) error {
+			if checkpoint != nil && isTopLevel(rootPath, osPathname) {
+				checkpoint.complete(filepath.Base(osPathname))
+			}
 			return nil
 		},
-		// Default error callback will just skip over when encountering errors
+		// Default error callback will just skip over when encountering errors, reporting them only in JSON mode
 		ErrorCallback: func(osPathname string, err error) godirwalk.ErrorAction {
+			if err == errAlertBudget {
+				return godirwalk.Halt
+			}
+			if os.IsNotExist(err) {
+				vanishedTotal++
+				reportSkip(osPathname, skipVanished, nil)
+				return godirwalk.SkipNode
+			}
+			if os.IsPermission(err) {
+				inaccessibleTotal++
+				warnInaccessible()
+				reportSkip(osPathname, skipPermission, nil)
+			} else {
+				reportSkip(osPathname, skipError, nil)
+			}
+			if *errorsJSONFlag {
+				reportError(osPathname, err)
+			}
+			failFast()
 			return godirwalk.SkipNode
 		},
 	})
 
-	// Close channels and cleanup routines
+	// Close channels and cleanup routines, keeping progress updates until counting is done
+	close(accurateChan)
+	accurateWg.Wait()
 	if *progressFlag {
 		doneTickerChan <- struct{}{}
 	}
 	doneSignalChan <- struct{}{}
-	close(accurateChan)
 	wg.Wait()
+	summary.addShrunk(shrunk)
+	summary.EntryTypes = append(summary.EntryTypes, breakdowns...)
+
+	// Moving directories away is safe only once the walk is done
+	if *quarantineDir != "" {
+		quarantineAll(verified)
+	}
+
+	if checkpoint != nil {
+		checkpoint.save()
+	}
+
+	output.Flush()
+	summary.Vanished += vanishedTotal
+	summary.Inaccessible += inaccessibleTotal
+
+	log.Printf("Found %v large directories in %q.", offenderTotal, redacted(rootPath))
+	if suspectTotal > 0 {
+		log.Printf("Found %v directories with suspect estimates in %q.", suspectTotal, redacted(rootPath))
+	}
+	if fanoutTotal > 0 {
+		log.Printf("Found %v large fan-out directories (extrapolated from samples) in %q.", fanoutTotal, redacted(rootPath))
+	}
+	if vanishedTotal > 0 {
+		log.Printf("Skipped %v directories that vanished during scan of %q.", vanishedTotal, redacted(rootPath))
+	}
+	if inaccessibleTotal > 0 {
+		log.Printf("Unable to access %v directories in %q.", inaccessibleTotal, redacted(rootPath))
+	}
+
+	summary.CrossChecks = append(summary.CrossChecks, rootSums.finish()...)
+}
+
+// statDir stats a directory found while walking, reporting whether it was removed in the meantime.
+func statDir(path string) (os.FileInfo, bool, error) {
+	fi, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, true, nil
+	}
+	return fi, false, err
+}
+
+// setupOutput will create a reporter for selected output format and destination.
+func setupOutput() {
+	if *outputDir != "" {
+		s, err := newSplitReporter(*outputDir, *outputFormat)
+		if err != nil {
+			reportError(*outputDir, err)
+			exit(1)
+		}
+		output = s
+
+		// Leave previous output files intact when exiting prematurely
+		atExit(s.Abort)
+	} else {
+		var w io.Writer = os.Stdout
+		switch {
+		case *outputFile == "-":
+		case *outputFile != "":
+			f, err := createAtomic(*outputFile)
+			if err != nil {
+				reportError(*outputFile, err)
+				exit(1)
+			}
+			outputAtomic = f
+			w = f
+
+			// Leave previous output file intact when exiting prematurely
+			atExit(f.Abort)
+		case *outputFormat == outputHuman && !*summaryJSONOnlyFlag:
+			w = os.Stderr
+		}
+
+		output = newReporter(*outputFormat, w)
+		if *summaryJSONOnlyFlag {
+			output = &summaryReporter{enc: json.NewEncoder(w)}
+		}
+	}
+
+	// Common prefix is computed over paths as displayed
+	if *compactPathsFlag {
+		output = &compactingReporter{reporter: output}
+	}
+
+	// Results are sorted by real paths and redacted only on their way out
+	if *redactMode != redactNone {
+		output = &redactingReporter{reporter: output, mode: *redactMode, salt: *redactSalt}
+	}
+
+	// Complete inventories are streamed unless explicitly asked to be sorted
+	key := *sortKey
+	if *reportEmptyFlag && !getopt.IsSet("sort") {
+		key = sortNone
+	}
+	switch {
+	case key == sortNone:
+	case *sortWindow > 0:
+		output = newWindowReporter(output, key, *reverseFlag, int(*sortWindow))
+	default:
+		output = &sortingReporter{reporter: output, key: key, reverse: *reverseFlag}
+	}
+}
+
+// closeOutput will write out the summary and move output file into place.
+func closeOutput() error {
+	outputClosed = true
+	if err := output.Close(&summary); err != nil {
+		if outputAtomic != nil {
+			outputAtomic.Abort()
+		}
+		return err
+	}
 
-	log.Printf("Found %v large directories in %q.", offenderTotal, rootPath)
+	if outputAtomic != nil {
+		return outputAtomic.Commit()
+	}
+	return nil
 }
 
-// humanPrint will display base10 approximate file count.
+// exitCalibration exits with an exit code telling why calibration failed.
+func exitCalibration(cal calibration) {
+	code, ok := calibrationExitCodes[cal.Failure]
+	if !ok {
+		code = calibrationExitCodes[calFailError]
+	}
+	log.Printf("Exiting with error as calibration of the first root failed (%v).", cal.Failure)
+	exit(code)
+}
+
+// limitWorkers caps concurrency of calibration file creation to stay safely under the open files limit.
+func limitWorkers() {
+	limit, ok := openFilesLimit(*raiseOpenFilesFlag)
+	if !ok {
+		return
+	}
+
+	// Walker, accurate counting, output and profiling hold a few descriptors at any time
+	max := 1
+	if limit > reservedOpenFiles+1 {
+		max = int(limit - reservedOpenFiles)
+	}
+	if calibrationWorkers > max {
+		log.Printf("Warning: lowering calibration concurrency from %v to %v to stay under open files limit of %v.",
+			calibrationWorkers, max, limit)
+		calibrationWorkers = max
+	}
+}
+
+// writePartialResults writes out results gathered so far when exiting early, marking the scan as incomplete.
+func writePartialResults() {
+	if outputClosed {
+		return
+	}
+
+	log.Printf("Writing out results gathered so far, scan is incomplete.")
+	summary.Incomplete = true
+	summary.Elapsed = time.Since(summary.Started)
+	summary.CPU, summary.PeakMemory = cpuTime(), peakMemory()
+	output.Flush()
+	if err := closeOutput(); err != nil {
+		reportError(*outputFile, err)
+	}
+}
+
+// addResult labels a single result with its device and reports it, unless it is outside of the age window.
+func addResult(r Result, fi os.FileInfo) bool {
+	r.Device = getDev(fi)
+	if !inAgeWindow(r.Path, fi) || !inEstimateBand(r) {
+		rootSums.add(r.Device, r.Path, r.Estimate)
+		return false
+	}
+
+	r.Label, r.Tags = deviceLabel(r.Device, r.Path), tags
+	if severityTiers() {
+		r.Severity = severityOf(r)
+	}
+	if *emitCleanupFlag && r.Kind == resultLarge {
+		r.Cleanup = cleanupCommand(r.Path)
+	}
+	if info, err := fsCache.Get(r.Device, r.Path); err == nil {
+		r.FSType, r.RAMBacked = info.FSType, info.IsRAM()
+	}
+	if r.Kind != resultScanned {
+		r.InodePercent = inodePercent(r.Device, r.Path, r.Estimate)
+		if *fsContextFlag && r.Kind != resultSkipped {
+			r.Filesystem = filesystemContext(r.Device, r.Path)
+		}
+	}
+	if !measureStart.IsZero() {
+		r.MeasureMS = float64(time.Since(measureStart).Microseconds()) / 1000
+	}
+	if r.Kind == resultLarge || r.Kind == resultFanout {
+		pressureSums.add(r.Device, r.Path, r.Estimate)
+	}
+	if checkpoint != nil {
+		checkpoint.result(r)
+	}
+	reportResult(r)
+	if r.Kind == resultLarge || r.Kind == resultFanout {
+		flagMeasureSpan(strAttr("kind", r.Kind), intAttr("device", int64(r.Device)), strAttr("device_label", redactedLabel(r.Label)),
+			intAttr("estimate", r.Estimate))
+	}
+	return true
+}
+
+// reportMeasure displays how long measuring a directory took, warning about outliers above slow-threshold.
+func reportMeasure(path string, d time.Duration) {
+	if *slowThreshold > 0 && d >= *slowThreshold {
+		log.Printf("Warning: directory %q was slow to measure, took %v (above %v).", redacted(path), formatDuration(d),
+			formatDuration(*slowThreshold))
+		return
+	}
+	log.Printf("Measured directory %q in %v.", redacted(path), formatDuration(d))
+}
+
+// inodePercent returns an estimate as a percentage of total inodes of the filesystem, or 0 if unknown.
+func inodePercent(dev uint64, path string, estimate int64) float64 {
+	total, ok := inodeTotals[dev]
+	if !ok {
+		if usage, err := fsinfo.Statfs(path); err == nil {
+			total = usage.Files
+		}
+		inodeTotals[dev] = total
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return float64(estimate) * 100 / float64(total)
+}
+
+// exceedsInodePercent checks an estimate against inode percentage threshold, if set.
+func exceedsInodePercent(path string, fi os.FileInfo, estimate int64) bool {
+	return *inodePercentThreshold > 0 && inodePercent(getDev(fi), path, estimate) >= *inodePercentThreshold
+}
+
+// inEstimateBand checks if an estimate of a flagged directory is within min-estimate and max-estimate bounds.
+func inEstimateBand(r Result) bool {
+	if r.Kind == resultScanned || r.Kind == resultSkipped {
+		return true
+	}
+	if *minEstimateFilter > 0 && r.Estimate < *minEstimateFilter {
+		return false
+	}
+	if *maxEstimateFilter > 0 && r.Estimate > *maxEstimateFilter {
+		return false
+	}
+	return true
+}
+
+// inAgeWindow checks if a directory was created (or modified, if creation time is unknown) within the window
+// given by newer-than and older-than durations.
+func inAgeWindow(path string, fi os.FileInfo) bool {
+	if *newerThan <= 0 && *olderThan <= 0 {
+		return true
+	}
+
+	t, ok := birthTime(path, fi)
+	if !ok {
+		t = fi.ModTime()
+		if !birthTimeWarned {
+			birthTimeWarned = true
+			log.Printf("Note: creation time is not available on %q, using modification time instead.", redacted(path))
+		}
+	}
+
+	age := summary.Started.Sub(t)
+	if *newerThan > 0 && age > *newerThan {
+		return false
+	}
+	if *olderThan > 0 && age < *olderThan {
+		return false
+	}
+	return true
+}
+
+// reportResult reports a single result and accounts it in the summary.
+func reportResult(r Result) {
+	rootSums.add(r.Device, r.Path, r.Estimate)
+	summary.addResult(r)
+	if !withinAlertBudget(r) {
+		return
+	}
+	recordMetric(r)
+	output.Result(r)
+}
+
+// isTopLevel checks if a path is a direct child of root.
+func isTopLevel(rootPath, path string) bool {
+	return path != rootPath && filepath.Dir(path) == rootPath
+}
+
+// warnInaccessible warns once per run that results of an unprivileged scan may be incomplete.
+func warnInaccessible() {
+	if inaccessibleWarned || os.Geteuid() == 0 {
+		return
+	}
+	inaccessibleWarned = true
+	log.Printf("Warning: not running as root and some directories are inaccessible, results may be incomplete.")
+}
+
+// flaggedAction tells the walker whether to descend into a flagged directory, which by default is pruned.
+func flaggedAction() error {
+	if *descendFlaggedFlag {
+		return nil
+	}
+	return godirwalk.SkipThis
+}
+
+// confirmEstimate replaces an estimate below min-entries-for-accurate, or any with confirm-with-accurate, with an
+// exact count, reporting if it did.
+func confirmEstimate(path string, estimate int64) (int64, bool) {
+	if estimate >= *minEntriesForAccurate && !*confirmAccurateFlag {
+		return estimate, false
+	}
+
+	count, err := countEntries(path, *countKindFlag, *countHiddenFlag, nil)
+	if err != nil {
+		reportError(path, err)
+		return estimate, false
+	}
+	return int64(count), true
+}
+
+// reportScanned reports a directory below threshold when every scanned directory is requested.
+func reportScanned(path string, estimate int64, ratio float64, fi os.FileInfo) {
+	if *reportEmptyFlag {
+		addResult(Result{Path: path, Kind: resultScanned, Estimate: estimate, Ratio: ratio}, fi)
+		return
+	}
+	rootSums.add(getDev(fi), path, estimate)
+}
+
+// reportSkip reports a directory the walk chose not to measure, if requested. Device is unknown without fi.
+func reportSkip(path, reason string, fi os.FileInfo) {
+	if !*reportSkipsFlag {
+		return
+	}
+
+	r := Result{Path: path, Kind: resultSkipped, SkipReason: reason, Tags: tags}
+	if fi != nil {
+		r.Device = getDev(fi)
+		r.Label = deviceLabel(r.Device, path)
+	}
+	summary.addResult(r)
+	output.Result(r)
+}
+
+// getCalibration returns calibration of the filesystem an entry resides on, calibrating each filesystem only once.
+func getCalibration(fi os.FileInfo, path string) calibration {
+	// Roots are separate filesystems, no need to track devices
+	dev := getDev(fi)
+	if !*rootsAreFilesystemsFlag {
+		if cal, ok := ratioCache[dev]; ok {
+			return cal
+		}
+	}
+
+	cal := calibration{Path: path, Failure: calFailPermission}
+	if dir, ok := calibrationDir(path, dev); ok {
+		cal = getInodeRatio(dir)
+		if *verifyRatioFlag && cal.Ratio > 0 {
+			cal = verifyCalibration(cal, dir, *verifyRatioTolerance)
+		}
+	}
+	cal.Device = dev
+	if info, err := fsCache.Get(dev, path); err == nil {
+		cal.FSType = info.FSType
+		if info.IsOverlay() {
+			log.Printf("Warning: %q is on overlay filesystem, estimates reflect the merged view rather than where entries physically live. Use --overlay-underlying to scan underlying directories instead.",
+				redacted(path))
+		}
+	}
+	summary.addDrift(cal, path)
+	summary.Calibrations = append(summary.Calibrations, cal)
+
+	// Failed calibrations are cached as well to avoid retrying on every directory
+	if !*rootsAreFilesystemsFlag {
+		ratioCache[dev] = cal
+	}
+	return cal
+}
+
+// nestedCalibration returns calibration of a filesystem mounted below a root. Pseudo and network filesystems are never
+// written to, their directories are checked with the ratio of the filesystem they are mounted on instead, same as
+// local filesystems that fail to calibrate.
+func nestedCalibration(fi os.FileInfo, path string, rootStat os.FileInfo, rootCal calibration) calibration {
+	dev := getDev(fi)
+	if cal, ok := borrowedRatios[dev]; ok {
+		return cal
+	}
+	info, err := fsCache.Get(dev, path)
+	local := err != nil || info.IsLocal()
+	if local {
+		if cal := getCalibration(fi, path); cal.Ratio > 0 || cal.Count {
+			return cal
+		}
+	}
+
+	// Walk enters a filesystem at its mount point, so its parent is on the filesystem it is mounted on
+	cal := rootCal
+	parent := filepath.Dir(path)
+	if pfi, err := os.Lstat(parent); err == nil && !isSameFilesystem(rootStat, pfi) {
+		cal = nestedCalibration(pfi, parent, rootStat, rootCal)
+	}
+	if local {
+		log.Printf("Warning: unable to calculate inode to file count ratio on %q, checking it with the ratio of the filesystem it is mounted on instead.",
+			redacted(path))
+		failFast()
+	} else {
+		log.Printf("Directory %q is on %v filesystem, checking it with the ratio of the filesystem it is mounted on instead of calibrating.",
+			redacted(path), info.FSType)
+	}
+	borrowedRatios[dev] = cal
+	return cal
+}
+
+// isPlausibleEstimate checks estimate against a configured ceiling and number of inodes in use on the filesystem,
+// returning the exceeded limit if estimate is impossible.
+func isPlausibleEstimate(path string, estimate int64) (int64, bool) {
+	if *maxEstimate > 0 && estimate > *maxEstimate {
+		return *maxEstimate, false
+	}
+
+	// Some filesystems don't report inode counts at all, skip the check then
+	usage, err := fsinfo.Statfs(path)
+	if err != nil {
+		return 0, true
+	}
+	if used := int64(usage.UsedFiles()); used > 0 && estimate > used {
+		return used, false
+	}
+
+	return 0, true
+}
+
+// humanPrint will display base10 approximate file count, or a rounded one if requested.
 func humanPrint(input int64) string {
+	if *roundTo > 0 || *sigFigs > 0 {
+		return "~" + formatCount(roundEstimate(input, *roundTo, *sigFigs))
+	}
+
 	exp := math.Round(math.Log(float64(input)) / math.Log(float64(10)))
 
 	switch {
@@ -259,9 +1371,68 @@ func humanPrint(input int64) string {
 	return "<1k"
 }
 
+// roundEstimate rounds a count to significant figures and then to nearest multiple of a value, where given.
+func roundEstimate(n, to, sig int64) int64 {
+	if sig > 0 {
+		if digits := int64(len(strconv.FormatInt(n, 10))); digits > sig {
+			p := int64(math.Pow10(int(digits - sig)))
+			n = int64(math.Round(float64(n)/float64(p))) * p
+		}
+	}
+	if to > 0 {
+		n = int64(math.Round(float64(n)/float64(to))) * to
+	}
+	return n
+}
+
+// Accurate counting kinds
+const (
+	countFiles = "files"
+	countDirs  = "dirs"
+	countAll   = "all"
+)
+
+var countKindNames = map[string]string{countFiles: "files", countDirs: "subdirectories", countAll: "entries"}
+
+// countKind counts directory entries of a given kind, files being everything but directories. Hidden entries are
+// dot-prefixed ones.
+func countKind(des godirwalk.Dirents, kind string, hidden bool) int {
+	if kind == countAll && hidden {
+		return len(des)
+	}
+
+	var n int
+	for _, de := range des {
+		if !hidden && strings.HasPrefix(de.Name(), ".") {
+			continue
+		}
+		if kind == countAll || de.IsDir() == (kind == countDirs) {
+			n++
+		}
+	}
+	return n
+}
+
+// fsDescription returns filesystem type and mountpoint for an entry, resolved through device id cache.
+func fsDescription(fi os.FileInfo, path string) string {
+	info, err := fsCache.Get(getDev(fi), path)
+	if err != nil {
+		return "unknown filesystem"
+	}
+	return fmt.Sprintf("%v filesystem on %q", info.FSType, redactedLabel(deviceLabel(info.Dev, path)))
+}
+
+// peakMemory returns memory obtained from the operating system by Go runtime, which it rarely returns.
+func peakMemory() uint64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.Sys
+}
+
 // printPath will display path processing progress.
 func printPath(processPath *string) {
 	if processPath != nil && *processPath != "" {
-		log.Printf("Last processed path was: %q.", *processPath)
+		log.Printf("Last processed path was: %q.", redacted(*processPath))
 	}
+	counting.print()
 }
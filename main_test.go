@@ -0,0 +1,328 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"github.com/karrick/godirwalk"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatDirVanished(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vanished")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "spool"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Directory is removed after being enumerated and before being measured
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "spool")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range entries {
+		fi, vanished, err := statDir(filepath.Join(dir, e.Name()))
+		if err != nil || !vanished || fi != nil {
+			t.Errorf("statDir(%q) = %v, %v, %v; want vanished without error", e.Name(), fi, vanished, err)
+		}
+	}
+
+	fi, vanished, err := statDir(dir)
+	if err != nil || vanished || fi == nil || !fi.IsDir() {
+		t.Errorf("statDir(%q) = %v, %v, %v; want existing directory", dir, fi, vanished, err)
+	}
+}
+
+func TestInAgeWindow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "age")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fi, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { *newerThan, *olderThan = 0, 0 }()
+	for _, tt := range []struct {
+		newer, older time.Duration
+		want         bool
+	}{
+		{0, 0, true},
+		{time.Hour, 0, true},
+		{0, time.Hour, false},
+		{2 * time.Hour, time.Hour, false},
+	} {
+		*newerThan, *olderThan = tt.newer, tt.older
+		if got := inAgeWindow(dir, fi); got != tt.want {
+			t.Errorf("inAgeWindow() with newer-than %v and older-than %v = %v; want %v", tt.newer, tt.older, got,
+				tt.want)
+		}
+	}
+}
+
+func TestInodePercent(t *testing.T) {
+	inodeTotals[42], inodeTotals[43] = 1000, 0
+	defer func() {
+		delete(inodeTotals, 42)
+		delete(inodeTotals, 43)
+	}()
+
+	if got := inodePercent(42, "/nonexistent", 50); got != 5 {
+		t.Errorf("inodePercent() = %v; want 5", got)
+	}
+	if got := inodePercent(43, "/nonexistent", 50); got != 0 {
+		t.Errorf("inodePercent() without inode counts = %v; want 0", got)
+	}
+}
+
+func TestCountKind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"a", "b", "c"} {
+		if err := os.Mkdir(filepath.Join(dir, "dir_"+name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"a", "b"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, "file_"+name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("file_a", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, ".hidden"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	des, err := godirwalk.ReadDirents(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for kind, want := range map[string]int{countAll: 7, countDirs: 3, countFiles: 4} {
+		if got := countKind(des, kind, true); got != want {
+			t.Errorf("countKind(%v) = %v; want %v", kind, got, want)
+		}
+	}
+	for kind, want := range map[string]int{countAll: 6, countDirs: 3, countFiles: 3} {
+		if got := countKind(des, kind, false); got != want {
+			t.Errorf("countKind(%v) without hidden = %v; want %v", kind, got, want)
+		}
+	}
+}
+
+func TestReportSkip(t *testing.T) {
+	saved := output
+	defer func() { output, *reportSkipsFlag = saved, false }()
+
+	var buf bytes.Buffer
+	output = newReporter(outputNDJSON, &buf)
+	reportSkip("/quiet", skipVanished, nil)
+	if buf.Len() != 0 {
+		t.Errorf("reportSkip() without --report-skips wrote %q; want nothing", buf.String())
+	}
+
+	*reportSkipsFlag = true
+	reportSkip("/gone", skipVanished, nil)
+	if want := `{"path":"/gone","kind":"skipped","estimate":0,"device":0,"device_label":"","skip_reason":"vanished"}` + "\n"; buf.String() != want {
+		t.Errorf("reportSkip() wrote %q; want %q", buf.String(), want)
+	}
+}
+
+func TestWritePartialResults(t *testing.T) {
+	savedOutput, savedSummary := output, summary
+	defer func() { output, summary, outputClosed = savedOutput, savedSummary, false }()
+
+	var buf bytes.Buffer
+	output = &sortingReporter{reporter: newReporter(outputNDJSON, &buf), key: sortEstimate}
+	summary = Summary{}
+	output.Result(Result{Path: "/a", Kind: resultLarge, Estimate: 100})
+
+	writePartialResults()
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 || !bytes.Contains(lines[0], []byte(`"path":"/a"`)) ||
+		!bytes.Contains(lines[1], []byte(`"incomplete":true`)) {
+		t.Errorf("writePartialResults() output = %q; want buffered result and incomplete summary", buf.String())
+	}
+
+	// Output already closed is left alone
+	buf.Reset()
+	writePartialResults()
+	if buf.Len() != 0 {
+		t.Errorf("writePartialResults() after close wrote %q; want nothing", buf.String())
+	}
+}
+
+func TestRoundEstimate(t *testing.T) {
+	for _, tt := range []struct {
+		n, to, sig int64
+		want       int64
+	}{
+		{1000347, 0, 0, 1000347},
+		{1000347, 1000, 0, 1000000},
+		{1000500, 1000, 0, 1001000},
+		{1234567, 0, 2, 1200000},
+		{1250000, 0, 2, 1300000},
+		{987, 0, 5, 987},
+		{1234567, 1000, 3, 1230000},
+	} {
+		if got := roundEstimate(tt.n, tt.to, tt.sig); got != tt.want {
+			t.Errorf("roundEstimate(%v, %v, %v) = %v; want %v", tt.n, tt.to, tt.sig, got, tt.want)
+		}
+	}
+}
+
+func TestConfirmEstimate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "small")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"a", "b", "c"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	*minEntriesForAccurate = 500
+	defer func() { *minEntriesForAccurate = 0 }()
+
+	if got, counted := confirmEstimate(dir, 400); got != 3 || !counted {
+		t.Errorf("confirmEstimate(400) = %v, %v; want 3, true", got, counted)
+	}
+	if got, counted := confirmEstimate(dir, 600); got != 600 || counted {
+		t.Errorf("confirmEstimate(600) = %v, %v; want 600, false", got, counted)
+	}
+
+	*confirmAccurateFlag = true
+	defer func() { *confirmAccurateFlag = false }()
+	if got, counted := confirmEstimate(dir, 600); got != 3 || !counted {
+		t.Errorf("confirmEstimate(600) confirming alerts = %v, %v; want 3, true", got, counted)
+	}
+}
+
+func TestReportMeasure(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	reportMeasure("/fast", time.Millisecond)
+	reportMeasure("/slow", time.Second*3)
+	if got := buf.String(); !strings.Contains(got, `Measured directory "/fast" in 1ms.`) ||
+		!strings.Contains(got, `Warning: directory "/slow" was slow to measure, took 3s (above 1s).`) {
+		t.Errorf("reportMeasure() logged %q; want /fast timing and /slow warning", got)
+	}
+}
+
+func TestInEstimateBand(t *testing.T) {
+	*minEstimateFilter, *maxEstimateFilter = 50000, 500000
+	defer func() { *minEstimateFilter, *maxEstimateFilter = 0, 0 }()
+
+	for _, tt := range []struct {
+		r    Result
+		want bool
+	}{
+		{Result{Kind: resultLarge, Estimate: 100000}, true},
+		{Result{Kind: resultLarge, Estimate: 40000}, false},
+		{Result{Kind: resultFanout, Estimate: 600000}, false},
+		{Result{Kind: resultSuspect, Estimate: 500000}, true},
+		{Result{Kind: resultScanned, Estimate: 10}, true},
+	} {
+		if got := inEstimateBand(tt.r); got != tt.want {
+			t.Errorf("inEstimateBand(%v %v) = %v; want %v", tt.r.Kind, tt.r.Estimate, got, tt.want)
+		}
+	}
+}
+
+func TestNestedCalibrationPseudo(t *testing.T) {
+	rootStat, err := os.Lstat("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Lstat("/proc")
+	if err != nil {
+		t.Skip("no /proc filesystem")
+	}
+	info, err := fsCache.Get(getDev(fi), "/proc")
+	if err != nil || !info.IsPseudo() || isSameFilesystem(rootStat, fi) {
+		t.Skip("/proc is not a separate pseudo filesystem")
+	}
+	defer delete(borrowedRatios, getDev(fi))
+
+	calibrations := len(summary.Calibrations)
+	rootCal := calibration{Ratio: 42}
+	if cal := nestedCalibration(fi, "/proc", rootStat, rootCal); cal.Ratio != 42 {
+		t.Errorf("nestedCalibration(/proc) = %+v; want ratio of the parent filesystem", cal)
+	}
+	if len(summary.Calibrations) != calibrations {
+		t.Errorf("nestedCalibration(/proc) calibrated %v filesystems; want none", len(summary.Calibrations)-calibrations)
+	}
+}
+
+func TestNestedCalibrationFailed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nested")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootStat, err := os.Lstat(filepath.Dir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev := getDev(fi)
+	defer func() {
+		delete(ratioCache, dev)
+		delete(borrowedRatios, dev)
+	}()
+
+	// Failed calibration is cached, as if the mount point was read-only
+	ratioCache[dev] = calibration{Path: dir, Failure: calFailPermission}
+	if cal := nestedCalibration(fi, dir, rootStat, calibration{Ratio: 42}); cal.Ratio != 42 {
+		t.Errorf("nestedCalibration() after failed calibration = %+v; want ratio of the parent filesystem", cal)
+	}
+}
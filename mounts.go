@@ -0,0 +1,58 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"sort"
+
+	"github.com/dkorunic/findlargedir/fsinfo"
+)
+
+// localMountpoints returns mount points of all local filesystems, skipping pseudo and network filesystems and
+// listing bind mounted devices and stacked mount points only once.
+func localMountpoints() ([]string, error) {
+	mounts, err := fsinfo.Mounts()
+	if err != nil {
+		return nil, err
+	}
+	return filterLocalMounts(mounts), nil
+}
+
+// filterLocalMounts picks local filesystems in mount point order, first mount point of each device winning.
+func filterLocalMounts(mounts []fsinfo.Info) []string {
+	sort.SliceStable(mounts, func(i, j int) bool {
+		return mounts[i].Mountpoint < mounts[j].Mountpoint
+	})
+
+	var res []string
+	seen := make(map[uint64]bool)
+	seenPath := make(map[string]bool)
+	for _, m := range mounts {
+		if !m.IsLocal() || seen[m.Dev] || seenPath[m.Mountpoint] {
+			continue
+		}
+		seen[m.Dev] = true
+		seenPath[m.Mountpoint] = true
+		res = append(res, m.Mountpoint)
+	}
+	return res
+}
@@ -0,0 +1,46 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dkorunic/findlargedir/fsinfo"
+)
+
+func TestFilterLocalMounts(t *testing.T) {
+	mounts := []fsinfo.Info{
+		{Dev: 1, Mountpoint: "/", FSType: "ext4"},
+		{Dev: 2, Mountpoint: "/proc", FSType: "proc"},
+		{Dev: 3, Mountpoint: "/srv/nfs", FSType: "nfs4"},
+		{Dev: 4, Mountpoint: "/home", FSType: "xfs"},
+		{Dev: 4, Mountpoint: "/var/home", FSType: "xfs"},
+		{Dev: 5, Mountpoint: "/boot", FSType: "vfat"},
+		{Dev: 6, Mountpoint: "/boot", FSType: "ext4"},
+	}
+
+	want := []string{"/", "/boot", "/home"}
+	if got := filterLocalMounts(mounts); !reflect.DeepEqual(got, want) {
+		t.Errorf("filterLocalMounts() = %v; want %v", got, want)
+	}
+}
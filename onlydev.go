@@ -0,0 +1,72 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// onlyDevices maps device ids the walk is limited to onto their mount points, empty when not limited.
+var onlyDevices = make(map[uint64]string)
+
+// parseOnlyDevices resolves mount points the walk is limited to into device ids.
+func parseOnlyDevices(list []string) error {
+	for _, v := range list {
+		fi, err := os.Stat(v)
+		if err != nil {
+			return fmt.Errorf("invalid only device mount point %q: %v", v, err)
+		}
+
+		abs, err := filepath.Abs(v)
+		if err != nil {
+			return fmt.Errorf("invalid only device mount point %q: %v", v, err)
+		}
+		onlyDevices[getDev(fi)] = abs
+	}
+	return nil
+}
+
+// deviceAllowed checks if a device is not filtered out by the device limit.
+func deviceAllowed(dev uint64) bool {
+	if len(onlyDevices) == 0 {
+		return true
+	}
+	_, ok := onlyDevices[dev]
+	return ok
+}
+
+// leadsToDevice checks if a directory holds a mount point of one of devices the walk is limited to.
+func leadsToDevice(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	for _, mp := range onlyDevices {
+		if isSubpath(abs, mp) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOnlyDevices(t *testing.T) {
+	defer func() { onlyDevices = make(map[uint64]string) }()
+
+	if !deviceAllowed(1) {
+		t.Errorf("deviceAllowed() without a device limit = false; want true")
+	}
+	if err := parseOnlyDevices([]string{"/nonexistent/mount"}); err == nil {
+		t.Errorf("parseOnlyDevices() with a missing mount point succeeded; want error")
+	}
+
+	dir := os.TempDir()
+	fi, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := parseOnlyDevices([]string{dir}); err != nil {
+		t.Fatal(err)
+	}
+	if !deviceAllowed(getDev(fi)) || deviceAllowed(getDev(fi)+1) {
+		t.Errorf("deviceAllowed() does not match only the listed device %v", getDev(fi))
+	}
+
+	abs, _ := filepath.Abs(dir)
+	if !leadsToDevice(filepath.Dir(abs)) || leadsToDevice(abs) || leadsToDevice(filepath.Join(abs, "sub")) {
+		t.Errorf("leadsToDevice() does not match only parents of %q", abs)
+	}
+}
@@ -0,0 +1,70 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// overlayRoots maps a root on overlay filesystem onto matching paths in its upper and lower directories, where
+// entries physically live. Roots elsewhere or with unknown underlying directories are kept as they are.
+func overlayRoots(root string) []string {
+	fi, err := os.Lstat(root)
+	if err != nil {
+		return []string{root}
+	}
+	info, err := fsCache.Get(getDev(fi), root)
+	if err != nil || !info.IsOverlay() {
+		return []string{root}
+	}
+
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return []string{root}
+	}
+	rel, err := filepath.Rel(info.Mountpoint, abs)
+	if err != nil {
+		return []string{root}
+	}
+
+	// Not every layer holds the root
+	upper, lower := info.OverlayDirs()
+	var roots []string
+	for _, dir := range append([]string{upper}, lower...) {
+		if dir == "" {
+			continue
+		}
+		p := filepath.Join(dir, rel)
+		if _, err := os.Lstat(p); err == nil {
+			roots = append(roots, p)
+		}
+	}
+
+	if len(roots) == 0 {
+		log.Printf("Unable to find directories underlying overlay filesystem of %q, scanning the merged view.", redacted(root))
+		return []string{root}
+	}
+	log.Printf("Root %q is on overlay filesystem, scanning underlying %q instead.", redacted(root), redactedAll(roots))
+	return roots
+}
@@ -0,0 +1,93 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+)
+
+// parametersHashLength is the number of hex digits of a parameters hash.
+const parametersHashLength = 12
+
+// newRunParameters returns effective scan parameters, once filters such as --only-device and --subtree are parsed.
+func newRunParameters() *runParameters {
+	var devices []string
+	for _, mp := range onlyDevices {
+		devices = append(devices, mp)
+	}
+	sort.Strings(devices)
+
+	return &runParameters{
+		Threshold:             *alertThreshold,
+		TestFileCount:         *testFileCount,
+		Accurate:              *accurateFlag,
+		OneFilesystem:         *oneFilesystemFlag,
+		CountKind:             *countKindFlag,
+		CritThreshold:         *critThreshold,
+		FSTypeThresholds:      fsTypeThresholds,
+		TmpfsThreshold:        *tmpfsThreshold,
+		InodePercentThreshold: *inodePercentThreshold,
+		FanoutThreshold:       *fanoutThreshold,
+		SampleSubdirs:         *sampleSubdirs,
+		MaxEstimate:           *maxEstimate,
+		MinEstimateFilter:     *minEstimateFilter,
+		MaxEstimateFilter:     *maxEstimateFilter,
+		NewerThan:             *newerThan,
+		OlderThan:             *olderThan,
+		EmptyTolerance:        *emptyTolerance,
+		MaxCalibrationFiles:   *maxCalibrationFiles,
+		Regression:            *calibrationRegressionFlag,
+		ConvergenceEpsilon:    *convergenceEpsilon,
+		ConvergenceStep:       *convergenceStep,
+		VerifyRatio:           *verifyRatioFlag,
+		VerifyTolerance:       *verifyRatioTolerance,
+		SalvageCalibration:    *salvageCalibrationFlag,
+		NoTempInTarget:        *noTempInTargetFlag,
+		RootsAreFilesystems:   *rootsAreFilesystemsFlag,
+		Isilon:                *isilonFlag,
+		CountFallback:         *countFallbackFlag,
+		CountHidden:           *countHiddenFlag,
+		CountBindMounts:       *countBindMountsFlag,
+		OverlayUnderlying:     *overlayUnderlyingFlag,
+		DescendFlagged:        *descendFlaggedFlag,
+		ReportEmpty:           *reportEmptyFlag,
+		ReportSkips:           *reportSkipsFlag,
+		MinEntriesForAccurate: *minEntriesForAccurate,
+		ConfirmAccurate:       *confirmAccurateFlag,
+		DeflagShrunk:          *deflagShrunkFlag,
+		MaxAlerts:             *maxAlerts,
+		StopAtMaxAlerts:       *stopAtMaxAlertsFlag,
+		MaxRuntimePerFS:       *maxRuntimePerFS,
+		OnlyDevices:           devices,
+		Subtrees:              subtrees,
+	}
+}
+
+// parametersHash returns a short hash of effective scan parameters, equal for runs with directly comparable results.
+func parametersHash(p runParameters) string {
+	b, _ := json.Marshal(p)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])[:parametersHashLength]
+}
@@ -0,0 +1,117 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParametersHash(t *testing.T) {
+	p := runParameters{Threshold: 50000, TestFileCount: 20000, CountKind: countAll}
+	h := parametersHash(p)
+	if len(h) != parametersHashLength || parametersHash(p) != h {
+		t.Errorf("parametersHash() = %q; want stable hash of %v hex digits", h, parametersHashLength)
+	}
+}
+
+func TestParametersHashOptions(t *testing.T) {
+	options := map[string]interface{}{
+		"threshold": alertThreshold, "testcount": testFileCount, "accurate": accurateFlag,
+		"onefilesystem": oneFilesystemFlag, "count-kind": countKindFlag, "crit-threshold": critThreshold,
+		"tmpfs-threshold": tmpfsThreshold, "inode-percent-threshold": inodePercentThreshold,
+		"fanout-threshold": fanoutThreshold, "sample-subdirs": sampleSubdirs, "max-file-count-estimate": maxEstimate,
+		"min-estimate": minEstimateFilter, "max-estimate": maxEstimateFilter, "newer-than": newerThan,
+		"older-than": olderThan, "empty-tolerance": emptyTolerance, "max-calibration-files": maxCalibrationFiles,
+		"calibration-regression": calibrationRegressionFlag, "convergence-epsilon": convergenceEpsilon,
+		"convergence-step": convergenceStep, "verify-ratio": verifyRatioFlag,
+		"verify-ratio-tolerance": verifyRatioTolerance, "salvage-calibration": salvageCalibrationFlag,
+		"no-temp-in-target": noTempInTargetFlag, "roots-are-filesystems": rootsAreFilesystemsFlag,
+		"isilon": isilonFlag, "count-fallback": countFallbackFlag, "count-hidden-in-estimate": countHiddenFlag,
+		"count-bind-mounts": countBindMountsFlag, "overlay-underlying": overlayUnderlyingFlag,
+		"descend-flagged": descendFlaggedFlag, "report-empty": reportEmptyFlag, "report-skips": reportSkipsFlag,
+		"min-entries-for-accurate": minEntriesForAccurate, "confirm-with-accurate": confirmAccurateFlag,
+		"deflag-shrunk": deflagShrunkFlag, "max-alerts": maxAlerts, "stop-at-max-alerts": stopAtMaxAlertsFlag,
+		"max-runtime-per-fs": maxRuntimePerFS,
+	}
+	lists := map[string]func() func(){
+		"threshold-by-fstype": func() func() {
+			fsTypeThresholds["xfs"] = 1000
+			return func() { delete(fsTypeThresholds, "xfs") }
+		},
+		"only-device": func() func() {
+			onlyDevices[1] = "/srv"
+			return func() { delete(onlyDevices, 1) }
+		},
+		"subtree": func() func() {
+			saved := subtrees
+			subtrees = []string{"/srv/mail"}
+			return func() { subtrees = saved }
+		},
+	}
+	if n := reflect.TypeOf(runParameters{}).NumField(); len(options)+len(lists) != n {
+		t.Fatalf("tested %v options; want one for each of %v run parameters", len(options)+len(lists), n)
+	}
+
+	h := parametersHash(*newRunParameters())
+	changed := func(name string) {
+		if got := parametersHash(*newRunParameters()); got == h {
+			t.Errorf("parametersHash() with another --%v = %q; want it to differ", name, got)
+		}
+	}
+	for name, opt := range options {
+		switch v := opt.(type) {
+		case *int64:
+			saved := *v
+			*v++
+			changed(name)
+			*v = saved
+		case *float64:
+			saved := *v
+			*v++
+			changed(name)
+			*v = saved
+		case *bool:
+			*v = !*v
+			changed(name)
+			*v = !*v
+		case *time.Duration:
+			saved := *v
+			*v += time.Hour
+			changed(name)
+			*v = saved
+		case *string:
+			saved := *v
+			*v = countFiles
+			changed(name)
+			*v = saved
+		default:
+			t.Fatalf("option --%v of unexpected type %T", name, opt)
+		}
+	}
+	for name, set := range lists {
+		restore := set()
+		changed(name)
+		restore()
+	}
+}
@@ -0,0 +1,105 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"log"
+
+	"github.com/dkorunic/findlargedir/fsinfo"
+)
+
+// An inodePressure projects entries of flagged directories on a filesystem against its free inodes.
+type inodePressure struct {
+	Path       string  `json:"path"`
+	Device     uint64  `json:"device"`
+	Label      string  `json:"device_label"`
+	Estimated  int64   `json:"estimated"`
+	FreeInodes uint64  `json:"free_inodes"`
+	Percent    float64 `json:"percent_of_free"`
+	AtRisk     bool    `json:"at_risk"`
+}
+
+// inodePressureSums sums estimates of flagged directories per device across all roots.
+type inodePressureSums struct {
+	devs []uint64
+	sums map[uint64]*inodePressure
+}
+
+var pressureSums = &inodePressureSums{sums: make(map[uint64]*inodePressure)}
+
+// add accounts a flagged directory estimate on a device, remembering the first directory seen on it.
+func (p *inodePressureSums) add(dev uint64, path string, estimate int64) {
+	if *inodePressureWarn <= 0 {
+		return
+	}
+
+	v, ok := p.sums[dev]
+	if !ok {
+		v = &inodePressure{Path: path, Device: dev}
+		p.sums[dev] = v
+		p.devs = append(p.devs, dev)
+	}
+	v.Estimated += estimate
+}
+
+// finish compares sums of every device against statfs free inode counts and warns about filesystems with flagged
+// entries reaching inode-pressure-warn percent of free inodes.
+func (p *inodePressureSums) finish() []inodePressure {
+	var res []inodePressure
+	for _, dev := range p.devs {
+		v := p.sums[dev]
+		usage, err := fsinfo.Statfs(v.Path)
+		if err != nil {
+			reportError(v.Path, err)
+			continue
+		}
+		// Filesystems without inode counts can't run out of them
+		if usage.Files == 0 {
+			continue
+		}
+
+		v.Label, v.FreeInodes = deviceLabel(dev, v.Path), usage.FreeFiles
+		if v.FreeInodes == 0 {
+			v.Percent, v.AtRisk = 100, true
+		} else {
+			v.Percent = float64(v.Estimated) / float64(v.FreeInodes) * 100
+			v.AtRisk = v.Percent >= *inodePressureWarn
+		}
+		if v.AtRisk {
+			log.Printf("Warning: flagged directories on %v hold %v entries, %.2f%% of its %v free inodes, filesystem is at risk of inode exhaustion.",
+				redactedLabel(v.Label), formatCount(v.Estimated), v.Percent, formatCount(int64(v.FreeInodes)))
+		}
+		res = append(res, *v)
+	}
+	return res
+}
+
+// addPressure records inode pressure of all filesystems in the summary. Filesystems at risk raise the worst severity
+// to warning when results are tagged with severities.
+func (s *Summary) addPressure(pressure []inodePressure) {
+	s.Pressure = append(s.Pressure, pressure...)
+	for _, v := range pressure {
+		if v.AtRisk && severityTiers() {
+			s.addSeverity(severityWarning, 1)
+		}
+	}
+}
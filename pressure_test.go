@@ -0,0 +1,66 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/dkorunic/findlargedir/fsinfo"
+)
+
+func TestInodePressure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pressure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	usage, err := fsinfo.Statfs(dir)
+	if err != nil || usage.Files == 0 || usage.FreeFiles == 0 {
+		t.Skip("filesystem has no free inode counts")
+	}
+
+	*inodePressureWarn, *warnThreshold = 50, 1000
+	defer func() { *inodePressureWarn, *warnThreshold = 0, 0 }()
+
+	for _, tt := range []struct {
+		estimate int64
+		risk     bool
+		severity string
+	}{
+		{1, false, ""},
+		{math.MaxInt64, true, severityWarning},
+	} {
+		p := &inodePressureSums{sums: make(map[uint64]*inodePressure)}
+		p.add(42, dir, tt.estimate)
+		var s Summary
+		s.addPressure(p.finish())
+		if len(s.Pressure) != 1 || s.Pressure[0].Estimated != tt.estimate || s.Pressure[0].AtRisk != tt.risk ||
+			s.Severity != tt.severity {
+			t.Errorf("addPressure(%v) = %+v with severity %q; want at risk %v with severity %q", tt.estimate,
+				s.Pressure, s.Severity, tt.risk, tt.severity)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+)
+
+var stopProfilingOnce sync.Once
+
+// startProfiling starts CPU profiling if requested, making sure profiles get written on premature exit too.
+func startProfiling() {
+	if *cpuProfile != "" {
+		f, err := os.Create(*cpuProfile)
+		if err != nil {
+			reportError(*cpuProfile, err)
+			exit(1)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			reportError(*cpuProfile, err)
+			_ = f.Close()
+			exit(1)
+		}
+	}
+
+	if *cpuProfile != "" || *memProfile != "" {
+		atExit(stopProfiling)
+	}
+}
+
+// stopProfiling flushes CPU profile and writes memory profile, only once.
+func stopProfiling() {
+	stopProfilingOnce.Do(func() {
+		if *cpuProfile != "" {
+			pprof.StopCPUProfile()
+		}
+
+		if *memProfile != "" {
+			f, err := os.Create(*memProfile)
+			if err != nil {
+				reportError(*memProfile, err)
+				return
+			}
+			defer f.Close()
+
+			// Get up-to-date statistics of live objects
+			runtime.GC()
+			if err := pprof.WriteHeapProfile(f); err != nil {
+				reportError(*memProfile, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,86 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"log"
+	"sort"
+	"sync"
+)
+
+// An entryProgress tracks exact entry counts in progress, so that progress updates stay meaningful while a single
+// huge directory is being counted. Counts of the walk and of accurate mode run concurrently, so they are kept by path.
+type entryProgress struct {
+	mu      sync.Mutex
+	counted map[string]int
+	updated func(path string, counted int)
+}
+
+var counting entryProgress
+
+// start begins tracking a count of a directory.
+func (p *entryProgress) start(path string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.counted == nil {
+		p.counted = make(map[string]int)
+	}
+	p.counted[path] = 0
+}
+
+// update records number of entries of a directory counted so far.
+func (p *entryProgress) update(path string, counted int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.counted[path] = counted
+	if p.updated != nil {
+		p.updated(path, counted)
+	}
+}
+
+// stop ends tracking a count of a directory.
+func (p *entryProgress) stop(path string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.counted, path)
+}
+
+// inProgress returns number of counts in progress.
+func (p *entryProgress) inProgress() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.counted)
+}
+
+// print displays counts in progress, if any.
+func (p *entryProgress) print() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	paths := make([]string, 0, len(p.counted))
+	for path := range p.counted {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		log.Printf("Counting entries of %q, %v counted so far.", redacted(path), p.counted[path])
+	}
+}
@@ -0,0 +1,98 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEntryProgress(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	var p entryProgress
+	p.start("/huge")
+	p.start("/big")
+	p.update("/huge", 123)
+	p.update("/big", 45)
+	p.print()
+	p.stop("/big")
+	p.stop("/huge")
+	p.print()
+	if got := buf.String(); !strings.Contains(got, `Counting entries of "/huge", 123 counted so far.`) ||
+		!strings.Contains(got, `Counting entries of "/big", 45 counted so far.`) || strings.Count(got, "Counting") != 2 {
+		t.Errorf("entryProgress.print() logged %q; want both concurrent counts in progress once", got)
+	}
+}
+
+func TestHugeSingleDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "huge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Calibration and a single estimate from st_size of one directory with millions of entries
+	saved := calFS
+	defer func() { calFS = saved }()
+	calFS = &fakeFS{empty: 4096, entry: 32}
+	cal := getInodeRatio(dir)
+	size := int64(4096 + 32*5000000)
+	if cal.Ratio != 32 || int64(float64(size)/cal.Ratio) != 5000128 {
+		t.Errorf("getInodeRatio() = %+v; want ratio 32 estimating 5000128 entries", cal)
+	}
+
+	// Accurate count reads it in small batches and reports progress along the way
+	for i := 0; i < 3000; i++ {
+		if err := ioutil.WriteFile(filepath.Join(dir, strconv.Itoa(i)), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func(n int64) { *readdirBatch = n }(*readdirBatch)
+	*readdirBatch = 16
+	defer func() { counting.updated = nil }()
+	for _, kind := range []string{countAll, countFiles} {
+		var partial []int
+		counting.updated = func(path string, counted int) {
+			if path == dir && counted > 0 && counted < 3000 {
+				partial = append(partial, counted)
+			}
+		}
+		if n, err := countEntries(dir, kind, true, nil); err != nil || n != 3000 {
+			t.Errorf("countEntries(%v) = %v, %v; want 3000", kind, n, err)
+		}
+		if len(partial) == 0 {
+			t.Errorf("countEntries(%v) reported no partial counts along the way", kind)
+		}
+	}
+	if n := counting.inProgress(); n != 0 {
+		t.Errorf("countEntries() left %v counts in progress", n)
+	}
+}
@@ -0,0 +1,119 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultPushgatewayJob = "findlargedir"
+const defaultPushgatewayTimeout = time.Second * 10
+
+// metricDirs holds large directories exported as per-directory metrics.
+var metricDirs []Result
+
+// recordMetric keeps a large directory for per-directory metrics, if pushing metrics.
+func recordMetric(r Result) {
+	if *pushgatewayURL != "" && r.Kind == resultLarge {
+		r.Path, r.Label = redacted(r.Path), redactedLabel(r.Label)
+		metricDirs = append(metricDirs, r)
+	}
+}
+
+// pushMetrics will push scan metrics to a Prometheus Pushgateway. Failures are logged but not fatal.
+func pushMetrics(s *Summary) {
+	if err := pushToGateway(*pushgatewayURL, *pushgatewayJob, s, metricDirs); err != nil {
+		log.Printf("Unable to push metrics to Pushgateway: %v", err)
+		return
+	}
+	log.Printf("Pushed metrics to Pushgateway %q as job %q.", *pushgatewayURL, *pushgatewayJob)
+}
+
+// pushToGateway replaces all metrics of a job grouping on a Pushgateway with current scan metrics, so series of
+// directories no longer flagged get removed.
+func pushToGateway(gatewayURL, job string, s *Summary, dirs []Result) error {
+	var body bytes.Buffer
+	writeMetrics(&body, s, dirs)
+
+	u := strings.TrimRight(gatewayURL, "/") + "/metrics/job/" + url.PathEscape(job)
+	req, err := http.NewRequest(http.MethodPut, u, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	if *pushgatewayUser != "" || *pushgatewayPassword != "" {
+		req.SetBasicAuth(*pushgatewayUser, *pushgatewayPassword)
+	}
+
+	client := &http.Client{Timeout: *pushgatewayTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response status %q", resp.Status)
+	}
+	return nil
+}
+
+// writeMetrics writes scan metrics and estimates of large directories in Prometheus text exposition format.
+func writeMetrics(w io.Writer, s *Summary, dirs []Result) {
+	metrics := []struct {
+		name, help string
+		value      float64
+	}{
+		{"findlargedir_largest_directory_entries", "Estimated entry count of the largest directory found.",
+			float64(s.Largest)},
+		{"findlargedir_large_directories", "Number of large directories found.", float64(s.Flagged)},
+		{"findlargedir_suspect_directories", "Number of directories with suspect estimates.", float64(s.Suspect)},
+		{"findlargedir_fanout_directories", "Number of large fan-out directories found.", float64(s.Fanout)},
+		{"findlargedir_scan_duration_seconds", "Duration of the whole scan in seconds.", s.Elapsed.Seconds()},
+	}
+
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v gauge\n%v %v\n", m.name, m.help, m.name, m.name, m.value)
+	}
+
+	if len(dirs) == 0 {
+		return
+	}
+	const name = "findlargedir_directory_entries"
+	fmt.Fprintf(w, "# HELP %v Estimated entry count of a large directory.\n# TYPE %v gauge\n", name, name)
+	for _, r := range dirs {
+		fmt.Fprintf(w, "%v{path=\"%v\",device_label=\"%v\"} %v\n", name, escapeLabel(r.Path),
+			escapeLabel(r.Label), r.Estimate)
+	}
+}
+
+// escapeLabel escapes a Prometheus label value.
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
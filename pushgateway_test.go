@@ -0,0 +1,92 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushToGateway(t *testing.T) {
+	var gotMethod, gotPath, gotBody, gotUser, gotPassword string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath = r.Method, r.URL.Path
+		gotUser, gotPassword, _ = r.BasicAuth()
+		b, _ := ioutil.ReadAll(r.Body)
+		gotBody = string(b)
+	}))
+	defer ts.Close()
+
+	*pushgatewayUser, *pushgatewayPassword = "user", "secret"
+	defer func() { *pushgatewayUser, *pushgatewayPassword = "", "" }()
+
+	s := &Summary{Flagged: 3, Largest: 123456, Elapsed: time.Second * 90}
+	dirs := []Result{{Path: `/srv/"odd"`, Kind: resultLarge, Estimate: 123456, Label: "/srv"}}
+	if err := pushToGateway(ts.URL+"/", "nightly scan", s, dirs); err != nil {
+		t.Fatal(err)
+	}
+
+	if gotMethod != http.MethodPut || gotPath != "/metrics/job/nightly scan" {
+		t.Errorf("pushToGateway() sent %v %q; want PUT /metrics/job/nightly scan", gotMethod, gotPath)
+	}
+	if gotUser != "user" || gotPassword != "secret" {
+		t.Errorf("pushToGateway() basic auth = %q:%q; want user:secret", gotUser, gotPassword)
+	}
+	for _, want := range []string{
+		"findlargedir_largest_directory_entries 123456\n",
+		"findlargedir_large_directories 3\n",
+		"findlargedir_scan_duration_seconds 90\n",
+		`findlargedir_directory_entries{path="/srv/\"odd\"",device_label="/srv"} 123456` + "\n",
+	} {
+		if !strings.Contains(gotBody, want) {
+			t.Errorf("pushToGateway() body missing %q:\n%v", want, gotBody)
+		}
+	}
+}
+
+func TestPushToGatewayError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	if err := pushToGateway(ts.URL, defaultPushgatewayJob, &Summary{}, nil); err == nil {
+		t.Error("pushToGateway() to a failing gateway returned nil error")
+	}
+}
+
+func TestRecordMetricRedacted(t *testing.T) {
+	savedURL, savedMode, savedDirs := *pushgatewayURL, *redactMode, metricDirs
+	defer func() { *pushgatewayURL, *redactMode, metricDirs = savedURL, savedMode, savedDirs }()
+	*pushgatewayURL, *redactMode, metricDirs = "http://localhost:9091", redactMask, nil
+
+	recordMetric(Result{Path: "/srv/mail/user", Kind: resultLarge, Label: "/srv/mail"})
+	recordMetric(Result{Path: "/srv/tmp", Kind: resultLarge, Label: "data"})
+	if len(metricDirs) != 2 || metricDirs[0].Path != "/srv/*/*" || metricDirs[0].Label != "/srv/*" ||
+		metricDirs[1].Path != "/srv/*" || metricDirs[1].Label != "data" {
+		t.Errorf("recordMetric() recorded %+v; want redacted paths and mount point labels", metricDirs)
+	}
+}
@@ -0,0 +1,224 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// scanRoots holds all roots of the program run, which are never quarantined.
+var scanRoots []string
+
+// quarantinePrompt asks for confirmation of each move, nil unless running interactively.
+var quarantinePrompt *prompt
+
+// quarantineAll moves verified large directories into quarantine directory, only displaying what would be moved
+// unless confirmed with --yes.
+func quarantineAll(dirs []string) {
+	for _, dir := range dirs {
+		if err := checkQuarantine(dir, *quarantineDir, scanRoots); err != nil {
+			log.Printf("Not quarantining %q: %v.", redacted(dir), err)
+			continue
+		}
+
+		dest, err := quarantineTarget(*quarantineDir, filepath.Base(dir))
+		if err != nil {
+			reportError(*quarantineDir, err)
+			continue
+		}
+
+		switch {
+		case quarantinePrompt != nil:
+			if !quarantinePrompt.confirm(fmt.Sprintf("Quarantine directory %q to %q?", dir, dest)) {
+				continue
+			}
+		case !*yesFlag:
+			log.Printf("Would quarantine directory %q to %q (dry run, use --yes to move).", redacted(dir), redacted(dest))
+			continue
+		}
+
+		log.Printf("Quarantining directory %q to %q, please wait...", redacted(dir), redacted(dest))
+		if err := moveDir(dir, dest); err != nil {
+			reportError(dir, err)
+			continue
+		}
+		summary.Quarantined++
+	}
+}
+
+// checkQuarantine verifies that a directory is neither a scan root nor holds one or the quarantine directory.
+func checkQuarantine(dir, quarantine string, roots []string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range roots {
+		r, err := filepath.Abs(r)
+		if err != nil {
+			return err
+		}
+		if r == abs || isSubpath(abs, r) {
+			return fmt.Errorf("it is or holds scan root %q", r)
+		}
+	}
+
+	q, err := filepath.Abs(quarantine)
+	if err != nil {
+		return err
+	}
+	if q == abs || isSubpath(abs, q) || isSubpath(q, abs) {
+		return fmt.Errorf("it overlaps quarantine directory %q", q)
+	}
+	return nil
+}
+
+// quarantineTarget returns a path in quarantine directory for a given name, adding a numeric suffix on collisions.
+func quarantineTarget(quarantine, name string) (string, error) {
+	dest := filepath.Join(quarantine, name)
+	for i := 1; ; i++ {
+		_, err := os.Lstat(dest)
+		if os.IsNotExist(err) {
+			return dest, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		dest = filepath.Join(quarantine, fmt.Sprintf("%v.%v", name, i))
+	}
+}
+
+// moveDir renames a directory, falling back to copying and removing it when moving across filesystems.
+func moveDir(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+		return err
+	}
+
+	err := os.Rename(src, dest)
+	if le, ok := err.(*os.LinkError); !ok || le.Err != syscall.EXDEV {
+		return err
+	}
+
+	if err := copyTree(src, dest); err != nil {
+		_ = os.RemoveAll(dest)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyTree copies a directory tree with its directories, regular files and symlinks.
+func copyTree(src, dest string) error {
+	return filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		switch mode := fi.Mode(); {
+		case mode.IsDir():
+			// Owner has to be able to fill it in
+			return os.Mkdir(target, mode.Perm()|0700)
+		case mode&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case mode.IsRegular():
+			return copyFile(path, target, mode.Perm())
+		}
+		return fmt.Errorf("unable to copy special file %q", path)
+	})
+}
+
+// copyFile copies contents of a regular file.
+func copyFile(src, dest string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// A prompt asks an operator for confirmation: yes, no (default), all remaining or quit for all remaining.
+type prompt struct {
+	in   *bufio.Reader
+	out  io.Writer
+	all  bool
+	quit bool
+}
+
+func newPrompt(in io.Reader, out io.Writer) *prompt {
+	return &prompt{in: bufio.NewReader(in), out: out}
+}
+
+// confirm asks a question until it gets a valid answer, treating end of input as quit.
+func (p *prompt) confirm(question string) bool {
+	for !p.all && !p.quit {
+		fmt.Fprintf(p.out, "%v [y/N/a/q] ", question)
+		line, err := p.in.ReadString('\n')
+		if err != nil && line == "" {
+			p.quit = true
+			break
+		}
+
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "y", "yes":
+			return true
+		case "", "n", "no":
+			return false
+		case "a", "all":
+			p.all = true
+		case "q", "quit":
+			p.quit = true
+		}
+	}
+	return p.all
+}
+
+// isTerminal checks if a file is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,126 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckQuarantine(t *testing.T) {
+	roots := []string{"/srv", "/data/nested/root"}
+	for _, tt := range []struct {
+		dir string
+		ok  bool
+	}{
+		{"/srv/cache", true},
+		{"/srv", false},
+		{"/data/nested", false},
+		{"/quarantine", false},
+		{"/quarantine/old", false},
+		{"/", false},
+	} {
+		if err := checkQuarantine(tt.dir, "/quarantine", roots); (err == nil) != tt.ok {
+			t.Errorf("checkQuarantine(%q) = %v; want ok %v", tt.dir, err, tt.ok)
+		}
+	}
+}
+
+func TestQuarantineTarget(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quarantine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"cache", "cache.1"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, want := range map[string]string{"cache": "cache.2", "spool": "spool"} {
+		if got, err := quarantineTarget(dir, name); err != nil || got != filepath.Join(dir, want) {
+			t.Errorf("quarantineTarget(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+}
+
+func TestCopyTree(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quarantine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src, "sub", "file"), []byte("content"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("sub/file", filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(dir, "dest")
+	if err := copyTree(src, dest); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(dest, "link")); err != nil || string(b) != "content" {
+		t.Errorf("copied tree file through symlink = %q, %v; want content", b, err)
+	}
+	if fi, err := os.Stat(filepath.Join(dest, "sub", "file")); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("copied file = %v, %v; want 0600 permissions", fi, err)
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	for _, tt := range []struct {
+		input string
+		want  []bool
+	}{
+		{"y\nn\n\nyes\n", []bool{true, false, false, true}},
+		{"maybe\nY\na\n", []bool{true, true, true, true}},
+		{"n\nq\n", []bool{false, false, false, false}},
+		{"y\n", []bool{true, false, false, false}},
+	} {
+		var out strings.Builder
+		p := newPrompt(strings.NewReader(tt.input), &out)
+
+		var got []bool
+		for range tt.want {
+			got = append(got, p.confirm("Move?"))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("confirm() answers to %q = %v; want %v", tt.input, got, tt.want)
+		}
+		if !strings.HasPrefix(out.String(), "Move? [y/N/a/q] ") {
+			t.Errorf("confirm() prompt = %q; want question with choices", out.String())
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// calRamp gates calibration workers while concurrency-ramp is in effect, nil otherwise.
+var calRamp *ramp
+
+// A ramp admits concurrent workers gradually, starting with one and opening another slot at regular intervals until
+// a target number of them is reached by the end of a duration.
+//
+// A ramp should be created with newRamp()
+type ramp struct {
+	slots chan struct{}
+	done  chan struct{}
+}
+
+// newRamp creates a new ramp up to target workers over a duration, or nil if there is nothing to ramp.
+func newRamp(target int, d time.Duration) *ramp {
+	if d <= 0 || target < 2 {
+		return nil
+	}
+
+	// Held slots are taken by the ramp itself and given up one at a time
+	r := &ramp{slots: make(chan struct{}, target), done: make(chan struct{})}
+	for i := 1; i < target; i++ {
+		r.slots <- struct{}{}
+	}
+	go r.open(target-1, d/time.Duration(target-1))
+	return r
+}
+
+// open gives up held slots one per interval.
+func (r *ramp) open(held int, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for ; held > 0; held-- {
+		select {
+		case <-t.C:
+		case <-r.done:
+			return
+		}
+		select {
+		case <-r.slots:
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// acquire waits for a free slot.
+func (r *ramp) acquire() {
+	if r != nil {
+		r.slots <- struct{}{}
+	}
+}
+
+// release frees a slot taken with acquire().
+func (r *ramp) release() {
+	if r != nil {
+		<-r.slots
+	}
+}
+
+// stop ends ramping up.
+func (r *ramp) stop() {
+	if r != nil {
+		close(r.done)
+	}
+}
+
+// startCalibrationRamp gates calibration workers of a single calibration with concurrency-ramp, if set.
+func startCalibrationRamp() {
+	calRamp = newRamp(calibrationWorkers, *concurrencyRamp)
+	if calRamp != nil {
+		log.Printf("Ramping up calibration concurrency from 1 to %v workers over %v.", calibrationWorkers,
+			*concurrencyRamp)
+	}
+}
+
+// stopCalibrationRamp ends ramping up calibration workers.
+func stopCalibrationRamp() {
+	calRamp.stop()
+	calRamp = nil
+}
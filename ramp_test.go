@@ -0,0 +1,67 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRamp(t *testing.T) {
+	if newRamp(1, time.Second) != nil || newRamp(4, 0) != nil {
+		t.Errorf("newRamp() without anything to ramp is not nil")
+	}
+
+	r := newRamp(4, 300*time.Millisecond)
+	defer r.stop()
+
+	var active, peak, early int32
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < 40; i++ {
+		r.acquire()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer r.release()
+			n := atomic.AddInt32(&active, 1)
+			if n > 1 && time.Since(start) < 50*time.Millisecond {
+				atomic.StoreInt32(&early, 1)
+			}
+			for p := atomic.LoadInt32(&peak); n > p && !atomic.CompareAndSwapInt32(&peak, p, n); {
+				p = atomic.LoadInt32(&peak)
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&active, -1)
+		}()
+	}
+	wg.Wait()
+
+	if early == 1 {
+		t.Errorf("ramp admitted several workers right away; want one")
+	}
+	if peak > 4 {
+		t.Errorf("ramp admitted %v concurrent workers; want at most 4", peak)
+	}
+}
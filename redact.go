@@ -0,0 +1,227 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Path redaction modes
+const (
+	redactNone = "none"
+	redactHash = "hash"
+	redactMask = "mask"
+)
+
+const redactHashLength = 12
+
+// redactPath replaces path components with stable salted hashes, or masks all but the top-level one, keeping the
+// tree shape either way.
+func redactPath(path, mode, salt string) string {
+	if mode == redactNone || path == "" {
+		return path
+	}
+
+	sep := string(filepath.Separator)
+	parts := strings.Split(path, sep)
+	var seen int
+	for i, p := range parts {
+		if p == "" {
+			continue
+		}
+		seen++
+
+		switch mode {
+		case redactHash:
+			sum := sha256.Sum256([]byte(salt + "\x00" + p))
+			parts[i] = hex.EncodeToString(sum[:])[:redactHashLength]
+		case redactMask:
+			if seen > 1 {
+				parts[i] = "*"
+			}
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// A redactingReporter redacts paths and path-like device labels before passing results and summary on.
+type redactingReporter struct {
+	reporter
+	mode string
+	salt string
+}
+
+func (r *redactingReporter) Result(res Result) {
+	r.reporter.Result(r.result(res))
+}
+
+func (r *redactingReporter) Close(s *Summary) error {
+	return r.reporter.Close(r.summary(s))
+}
+
+// summary returns a redacted copy of a summary.
+func (r *redactingReporter) summary(s *Summary) *Summary {
+	c := *s
+	c.LargestPath = r.path(s.LargestPath)
+	c.Top, c.Bottom = r.results(s.Top), r.results(s.Bottom)
+
+	c.CrossChecks = nil
+	for _, v := range s.CrossChecks {
+		v.Path, v.Label = r.path(v.Path), r.label(v.Label)
+		c.CrossChecks = append(c.CrossChecks, v)
+	}
+	c.Partial = nil
+	for _, v := range s.Partial {
+		v.Path, v.Label = r.path(v.Path), r.label(v.Label)
+		c.Partial = append(c.Partial, v)
+	}
+	c.Shrunk = nil
+	for _, v := range s.Shrunk {
+		v.Path = r.path(v.Path)
+		c.Shrunk = append(c.Shrunk, v)
+	}
+	c.EntryTypes = nil
+	for _, v := range s.EntryTypes {
+		v.Path = r.path(v.Path)
+		c.EntryTypes = append(c.EntryTypes, v)
+	}
+	c.Pressure = nil
+	for _, v := range s.Pressure {
+		v.Path, v.Label = r.path(v.Path), r.label(v.Label)
+		c.Pressure = append(c.Pressure, v)
+	}
+	c.Drift = nil
+	for _, v := range s.Drift {
+		v.Label = r.label(v.Label)
+		c.Drift = append(c.Drift, v)
+	}
+	c.Leftovers = r.paths(s.Leftovers)
+	if s.Parameters != nil {
+		p := *s.Parameters
+		p.OnlyDevices, p.Subtrees = r.paths(p.OnlyDevices), r.paths(p.Subtrees)
+		c.Parameters = &p
+	}
+	c.Calibrations = nil
+	for _, v := range s.Calibrations {
+		v.Path = r.path(v.Path)
+		c.Calibrations = append(c.Calibrations, v)
+	}
+
+	return &c
+}
+
+func (r *redactingReporter) path(path string) string {
+	return redactPath(path, r.mode, r.salt)
+}
+
+func (r *redactingReporter) paths(paths []string) []string {
+	var redacted []string
+	for _, p := range paths {
+		redacted = append(redacted, r.path(p))
+	}
+	return redacted
+}
+
+func (r *redactingReporter) label(label string) string {
+	return redactLabel(label, r.mode, r.salt)
+}
+
+// redactLabel redacts device labels holding mount points, leaving user supplied names and device ids alone.
+func redactLabel(label, mode, salt string) string {
+	if !filepath.IsAbs(label) {
+		return label
+	}
+	return redactPath(label, mode, salt)
+}
+
+// redacted redacts a path outside of reporter output, such as in log lines and pushed metrics.
+func redacted(path string) string {
+	return redactPath(path, *redactMode, *redactSalt)
+}
+
+// redactedSummary redacts a summary outside of reporter output, such as in the summary line.
+func redactedSummary(s *Summary) *Summary {
+	if *redactMode == redactNone {
+		return s
+	}
+	return (&redactingReporter{mode: *redactMode, salt: *redactSalt}).summary(s)
+}
+
+// redactedAll redacts paths outside of reporter output.
+func redactedAll(paths []string) []string {
+	res := make([]string, 0, len(paths))
+	for _, p := range paths {
+		res = append(res, redacted(p))
+	}
+	return res
+}
+
+// redactedError redacts a path and the path of an underlying path error in an error message.
+func redactedError(path string, err error) string {
+	msg := err.Error()
+	if *redactMode == redactNone {
+		return msg
+	}
+
+	var pe *os.PathError
+	if errors.As(err, &pe) && pe.Path != "" {
+		msg = strings.Replace(msg, pe.Path, redacted(pe.Path), -1)
+	}
+	if path != "" {
+		msg = strings.Replace(msg, path, redacted(path), -1)
+	}
+	return msg
+}
+
+// redactedLabel redacts a device label outside of reporter output.
+func redactedLabel(label string) string {
+	return redactLabel(label, *redactMode, *redactSalt)
+}
+
+func (r *redactingReporter) result(res Result) Result {
+	res.Path, res.Label = r.path(res.Path), r.label(res.Label)
+	if res.DuplicateOf != "" {
+		res.DuplicateOf = r.path(res.DuplicateOf)
+	}
+	if res.Cleanup != "" {
+		res.Cleanup = cleanupCommand(res.Path)
+	}
+	if res.Filesystem != nil {
+		fs := *res.Filesystem
+		fs.Mountpoint = r.path(fs.Mountpoint)
+		res.Filesystem = &fs
+	}
+	return res
+}
+
+func (r *redactingReporter) results(results []Result) []Result {
+	var redacted []Result
+	for _, v := range results {
+		redacted = append(redacted, r.result(v))
+	}
+	return redacted
+}
@@ -0,0 +1,84 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactPath(t *testing.T) {
+	if got := redactPath("/srv/mail/user", redactMask, ""); got != "/srv/*/*" {
+		t.Errorf("redactPath() masked = %q; want /srv/*/*", got)
+	}
+	if got := redactPath("/srv/mail", redactNone, ""); got != "/srv/mail" {
+		t.Errorf("redactPath() without redaction = %q; want /srv/mail", got)
+	}
+
+	a, b := redactPath("/srv/mail/user", redactHash, ""), redactPath("/srv/mail", redactHash, "")
+	if !strings.HasPrefix(a, b+"/") || strings.Count(a, "/") != 3 || strings.Contains(a, "mail") {
+		t.Errorf("redactPath() hashed = %q and %q; want stable hashes keeping tree shape", a, b)
+	}
+	if salted := redactPath("/srv/mail", redactHash, "secret"); salted == b {
+		t.Errorf("redactPath() hashed with salt = %q; want different from unsalted %q", salted, b)
+	}
+}
+
+func TestRedactingReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := &redactingReporter{reporter: newReporter(outputNDJSON, &buf), mode: redactMask}
+	r.Result(Result{Path: "/srv/mail/user", Kind: resultLarge, Estimate: 100, Label: "/srv/mail"})
+	s := &Summary{LargestPath: "/srv/mail/user", Top: []Result{{Path: "/srv/mail/user"}},
+		Shrunk: []shrunkDir{{Path: "/srv/mail/old"}}, EntryTypes: []entryBreakdown{{Path: "/srv/mail/user"}},
+		Parameters: &runParameters{OnlyDevices: []string{"/srv/mail"}, Subtrees: []string{"/srv/mail/user"}}}
+	if err := r.Close(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(buf.String(), "mail") || !strings.Contains(buf.String(), `"estimate":100`) {
+		t.Errorf("redacting reporter output = %q; want masked paths keeping estimates", buf.String())
+	}
+	if s.LargestPath != "/srv/mail/user" || s.Parameters.Subtrees[0] != "/srv/mail/user" {
+		t.Errorf("redacting reporter changed summary largest path to %q", s.LargestPath)
+	}
+}
+
+func TestRedactedLogs(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	*redactMode = redactMask
+	defer func() { *redactMode = redactNone }()
+
+	reportMeasure("/srv/mail/user", time.Millisecond)
+	_, err := os.Stat("/srv/mail/user/missing")
+	if got := redactedError("/srv/mail/user", err); strings.Contains(got, "mail") || !strings.Contains(got, "/srv/*") {
+		t.Errorf("redactedError() = %q; want masked path", got)
+	}
+	if strings.Contains(buf.String(), "mail") || !strings.Contains(buf.String(), `"/srv/*/*"`) {
+		t.Errorf("log output = %q; want masked path", buf.String())
+	}
+}
@@ -0,0 +1,173 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"math"
+
+	"github.com/dkorunic/findlargedir/cerrgroup"
+)
+
+// Regression calibration starts with this share of test files, doubling batches until the ratio is stable
+const regressionFirstBatchDivisor = 16
+const regressionMinBatch = 100
+const regressionStableDelta = 2
+
+// Convergence calibration checks the running ratio after this many test files by default
+const defaultConvergenceStep = 1000
+
+// createCalibrationFiles creates up to count test files in a directory, returning number of files created. With
+// regression calibration files are created in doubling batches and directory size is fit against file count after
+// each, stopping early once the ratio (slope of the fit) changes by less than regressionStableDelta percent, in which
+// case slope and coefficient of determination are returned as well.
+func createCalibrationFiles(dir string, count, emptySize int64) (created int64, slope, r2 float64, err error) {
+	if !*calibrationRegressionFlag {
+		if *convergenceEpsilon > 0 {
+			created, err = createConvergingFiles(dir, count, emptySize)
+			return created, 0, 0, err
+		}
+		return count, 0, 0, createFiles(dir, 0, count)
+	}
+
+	batch := count / regressionFirstBatchDivisor
+	if batch < regressionMinBatch {
+		batch = regressionMinBatch
+	}
+	xs, ys := []float64{0}, []float64{float64(emptySize)}
+	var prev float64
+	for created < count {
+		to := created + batch
+		if to > count {
+			to = count
+		}
+		if err = createFiles(dir, created, to); err != nil {
+			return created, 0, 0, err
+		}
+		created, batch = to, batch*2
+
+		size, err := calFS.DirSize(dir)
+		if err != nil {
+			reportError(dir, err)
+			return created, 0, 0, err
+		}
+		xs, ys = append(xs, float64(created)), append(ys, float64(size))
+
+		slope, r2 = linearFit(xs, ys)
+		if len(xs) > 2 && prev > 0 && ratioDelta(prev, slope) < regressionStableDelta {
+			break
+		}
+		prev = slope
+	}
+	return created, slope, r2, nil
+}
+
+// createConvergingFiles creates up to count test files in checkpoints of convergence-step files, stopping early once
+// the running ratio changes by less than convergence-epsilon percent between consecutive checkpoints.
+func createConvergingFiles(dir string, count, emptySize int64) (int64, error) {
+	step := *convergenceStep
+	if step <= 0 {
+		step = defaultConvergenceStep
+	}
+
+	var created int64
+	var prev float64
+	for created < count {
+		to := created + step
+		if to > count {
+			to = count
+		}
+		if err := createFiles(dir, created, to); err != nil {
+			return created, err
+		}
+		created = to
+
+		size, err := calFS.DirSize(dir)
+		if err != nil {
+			reportError(dir, err)
+			return created, err
+		}
+		ratio := float64(size-emptySize) / float64(created)
+		if prev > 0 && ratioDelta(prev, ratio) < *convergenceEpsilon {
+			break
+		}
+		prev = ratio
+	}
+	return created, nil
+}
+
+// createFiles concurrently creates test files numbered from up to to in a directory.
+func createFiles(dir string, from, to int64) error {
+	// Highly concurrent file creation routine with at most calibrationWorkers running routines
+	cg := cerrgroup.New(calibrationWorkers)
+	content := []byte(testContent)
+	for i := from; i < to && !calibrationStopped(); i++ {
+		var name string
+		if calFileName != nil {
+			name = calFileName(i)
+		}
+		calRamp.acquire()
+		cg.Go(func() error {
+			defer calRamp.release()
+			if name, err := calFS.CreateFile(dir, name, content); err != nil {
+				reportError(name, err)
+				return err
+			}
+			return nil
+		})
+	}
+	if err := cg.Wait(); err != nil {
+		return err
+	}
+	if calibrationStopped() {
+		return errCalibrationInterrupted
+	}
+	return nil
+}
+
+// linearFit returns slope of least squares line through points and its coefficient of determination (R²).
+func linearFit(xs, ys []float64) (slope, r2 float64) {
+	n := float64(len(xs))
+	var sx, sy, sxx, sxy float64
+	for i := range xs {
+		sx += xs[i]
+		sy += ys[i]
+		sxx += xs[i] * xs[i]
+		sxy += xs[i] * ys[i]
+	}
+	d := n*sxx - sx*sx
+	if d == 0 {
+		return 0, 0
+	}
+	slope = (n*sxy - sx*sy) / d
+	intercept := (sy - slope*sx) / n
+
+	var ssRes, ssTot float64
+	mean := sy / n
+	for i := range xs {
+		ssRes += math.Pow(ys[i]-(slope*xs[i]+intercept), 2)
+		ssTot += math.Pow(ys[i]-mean, 2)
+	}
+	if ssTot == 0 {
+		return slope, 0
+	}
+	return slope, 1 - ssRes/ssTot
+}
@@ -0,0 +1,89 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestLinearFit(t *testing.T) {
+	slope, r2 := linearFit([]float64{0, 1, 2, 3}, []float64{10, 12, 14, 16})
+	if slope != 2 || r2 != 1 {
+		t.Errorf("linearFit() of a line = %v, %v; want 2, 1", slope, r2)
+	}
+
+	slope, r2 = linearFit([]float64{0, 1, 2, 3}, []float64{0, 4, 4, 8})
+	if math.Abs(slope-2.4) > 1e-9 || r2 <= 0.8 || r2 >= 1 {
+		t.Errorf("linearFit() of steps = %v, %v; want 2.4 with R² below 1", slope, r2)
+	}
+}
+
+func TestRegressionCalibration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "calibration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	saved, savedCount := calFS, *testFileCount
+	defer func() { calFS, *testFileCount, *calibrationRegressionFlag = saved, savedCount, false }()
+	*testFileCount, *calibrationRegressionFlag = 20000, true
+
+	fs := &fakeFS{empty: 64, entry: 24}
+	calFS = fs
+
+	cal := getInodeRatio(dir)
+	if cal.Ratio != 24 || cal.RSquared != 1 {
+		t.Errorf("getInodeRatio() = %+v; want ratio 24 with R² of 1", cal)
+	}
+	if cal.TestFileCount != 3750 || fs.files != 3750 {
+		t.Errorf("getInodeRatio() created %v files (reported %v); want early stop after 3750", fs.files,
+			cal.TestFileCount)
+	}
+}
+
+func TestConvergingCalibration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "calibration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	saved, savedCount := calFS, *testFileCount
+	defer func() { calFS, *testFileCount, *convergenceEpsilon = saved, savedCount, 0 }()
+	*testFileCount, *convergenceEpsilon = 20000, 1
+
+	fs := &fakeFS{empty: 64, entry: 24}
+	calFS = fs
+
+	cal := getInodeRatio(dir)
+	if cal.Ratio != 24 || !cal.Converged {
+		t.Errorf("getInodeRatio() = %+v; want converged ratio 24", cal)
+	}
+	if cal.TestFileCount != 2*defaultConvergenceStep || fs.files != 2*defaultConvergenceStep {
+		t.Errorf("getInodeRatio() created %v files (reported %v); want early stop after %v", fs.files,
+			cal.TestFileCount, 2*defaultConvergenceStep)
+	}
+}
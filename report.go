@@ -0,0 +1,362 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats
+const (
+	outputHuman  = "human"
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+	outputCSV    = "csv"
+	outputYAML   = "yaml"
+)
+
+// A reporter writes results out in a particular output format.
+type reporter interface {
+	// Result reports a single result.
+	Result(r Result)
+	// Flush is called when all results of a single root have been reported.
+	Flush()
+	// Close finalizes output with a summary of the whole program run.
+	Close(s *Summary) error
+}
+
+// newReporter creates a reporter for a given output format.
+func newReporter(format string, w io.Writer) reporter {
+	switch format {
+	case outputJSON:
+		return &jsonReporter{w: w}
+	case outputNDJSON:
+		return &ndjsonReporter{enc: json.NewEncoder(w)}
+	case outputCSV:
+		return newCSVReporter(w)
+	case outputYAML:
+		return &yamlReporter{jsonReporter{w: w}}
+	}
+	return &humanReporter{logger: log.New(w, "", log.LstdFlags)}
+}
+
+// A humanReporter writes results as human readable log lines.
+type humanReporter struct {
+	logger  *log.Logger
+	grouped []Result
+	prefix  string
+}
+
+func (h *humanReporter) Result(r Result) {
+	// Grouped results are displayed once the root is done
+	if *groupByParentFlag && r.Kind == resultLarge {
+		h.grouped = append(h.grouped, r)
+		return
+	}
+	h.print("", r)
+}
+
+func (h *humanReporter) Flush() {
+	for _, g := range groupByParent(h.grouped) {
+		if len(g.Children) == 1 {
+			h.print("", g.Children[0])
+			continue
+		}
+
+		h.logger.Printf("Directory %q possibly holds %v large directories with %v entries combined.", g.Parent,
+			len(g.Children), humanPrint(g.Estimate))
+		if *verboseFlag {
+			for _, r := range g.Children {
+				h.print("  ", r)
+			}
+		}
+	}
+	h.grouped = nil
+}
+
+func (h *humanReporter) Close(s *Summary) error {
+	if s.ParamsHash != "" {
+		h.logger.Printf("Scan parameters hash is %v, results of runs with the same hash are directly comparable.",
+			s.ParamsHash)
+	}
+	for _, dir := range s.Leftovers {
+		h.logger.Printf("Warning: temporary directory %v could not be removed, remove it manually.", dir)
+	}
+	if s.RAMBacked > 0 {
+		h.logger.Printf("Flagged directories on memory backed filesystems hold an estimated %v entries consuming RAM.",
+			formatCount(s.RAMBacked))
+	}
+	if len(s.Buckets) > 0 {
+		var total int64
+		h.logger.Printf("Directories by estimated entries:")
+		for _, b := range s.Buckets {
+			h.logger.Printf("  %v: %v", b, formatCount(b.Count))
+			total += b.Count
+		}
+		h.logger.Printf("  total: %v", formatCount(total))
+	}
+	if len(s.Top) > 0 {
+		h.logger.Printf("Top %v largest directories:", len(s.Top))
+		for _, r := range s.Top {
+			h.print("  ", r)
+		}
+	}
+	if len(s.Bottom) > 0 {
+		h.logger.Printf("Bottom %v smallest flagged directories:", len(s.Bottom))
+		for _, r := range s.Bottom {
+			h.print("  ", r)
+		}
+	}
+	return nil
+}
+
+// print displays a single result with a given indentation prefix.
+func (h *humanReporter) print(prefix string, r Result) {
+	if r.Cleanup != "" {
+		defer h.logger.Printf("%v  Suggested cleanup (not run): %v", prefix, r.Cleanup)
+	}
+	if r.CommonPrefix != "" {
+		if r.CommonPrefix != h.prefix {
+			h.logger.Printf("%vPaths relative to %q:", prefix, r.CommonPrefix)
+			h.prefix = r.CommonPrefix
+		}
+		r.Path = relativePath(r.Path, r.CommonPrefix)
+	}
+
+	switch r.Kind {
+	case resultSuspect:
+		h.logger.Printf("%vDirectory %q has a suspect estimate of %v entries exceeding %v, inode ratio is most likely incorrect%v%v.",
+			prefix, r.Path, formatCount(r.Estimate), formatCount(r.Limit), fsNote(r), severityNote(r))
+	case resultScanned:
+		h.logger.Printf("%vDirectory %q has approximately %v entries.", prefix, r.Path, humanPrint(r.Estimate))
+	case resultSkipped:
+		if r.DuplicateOf != "" {
+			h.logger.Printf("%vDirectory %q was skipped (%v of %q).", prefix, r.Path, r.SkipReason, r.DuplicateOf)
+			return
+		}
+		h.logger.Printf("%vDirectory %q was skipped (%v).", prefix, r.Path, r.SkipReason)
+	case resultFanout:
+		h.logger.Printf("%vDirectory %q is a large fan-out directory with %v subdirectories holding an extrapolated %v entries (sampled %v)%v%v.",
+			prefix, r.Path, formatCount(int64(r.Subdirs)), humanPrint(r.Estimate), formatCount(int64(r.Sampled)), fsNote(r),
+			severityNote(r))
+	case resultLarge:
+		if r.Counted {
+			h.logger.Printf("%vDirectory %q is a large directory with %v counted entries%v%v%v%v.", prefix, r.Path,
+				humanPrint(r.Estimate), inodeShare(r), overlayNote(r), fsNote(r), severityNote(r))
+			return
+		}
+		fallthrough
+	default:
+		h.logger.Printf("%vDirectory %q is possibly a large directory with %v entries%v%v%v%v.", prefix, r.Path,
+			humanPrint(r.Estimate), inodeShare(r), overlayNote(r), fsNote(r), severityNote(r))
+	}
+}
+
+// inodeShare describes percentage of filesystem inode capacity used by a directory, if known.
+func inodeShare(r Result) string {
+	if r.InodePercent <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%.2f%% of filesystem inodes)", r.InodePercent)
+}
+
+// severityNote marks flagged results with their severity, if tagged.
+func severityNote(r Result) string {
+	if r.Severity == "" {
+		return ""
+	}
+	return fmt.Sprintf(" [%v]", r.Severity)
+}
+
+// overlayNote marks results on overlay filesystems, whose estimates reflect the merged view, and on memory backed
+// filesystems, whose entries consume RAM.
+func overlayNote(r Result) string {
+	switch {
+	case r.FSType == "overlay":
+		return " (merged overlay view)"
+	case r.RAMBacked:
+		return " (RAM-backed " + r.FSType + ")"
+	}
+	return ""
+}
+
+// A jsonReporter writes all results and summary as a single JSON document.
+type jsonReporter struct {
+	w       io.Writer
+	results []Result
+}
+
+func (j *jsonReporter) Result(r Result) {
+	j.results = append(j.results, r)
+}
+
+func (j *jsonReporter) Flush() {
+}
+
+func (j *jsonReporter) Close(s *Summary) error {
+	results := j.results
+	if results == nil {
+		results = []Result{}
+	}
+
+	enc := json.NewEncoder(j.w)
+	if *jsonPrettyFlag {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(struct {
+		Results      []Result      `json:"results"`
+		Calibrations []calibration `json:"calibrations"`
+		Summary      *Summary      `json:"summary"`
+	}{results, calibrations(s), s})
+}
+
+// A yamlReporter writes all results and summary as a single YAML document, with the same fields as JSON output.
+type yamlReporter struct {
+	jsonReporter
+}
+
+func (y *yamlReporter) Close(s *Summary) error {
+	results := y.results
+	if results == nil {
+		results = []Result{}
+	}
+
+	// Going through JSON keeps field names, order and encoding of the JSON schema
+	b, err := json.Marshal(struct {
+		Results      []Result      `json:"results"`
+		Calibrations []calibration `json:"calibrations"`
+		Summary      *Summary      `json:"summary"`
+	}{results, calibrations(s), s})
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	blockStyle(&doc)
+
+	enc := yaml.NewEncoder(y.w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// blockStyle resets styles inherited from JSON so that YAML is written in block style with plain scalars.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+// An ndjsonReporter streams results as JSON lines, followed by a summary line.
+type ndjsonReporter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonReporter) Result(r Result) {
+	_ = n.enc.Encode(r)
+}
+
+func (n *ndjsonReporter) Flush() {
+}
+
+func (n *ndjsonReporter) Close(s *Summary) error {
+	return n.enc.Encode(struct {
+		Calibrations []calibration `json:"calibrations"`
+		Summary      *Summary      `json:"summary"`
+	}{calibrations(s), s})
+}
+
+// A summaryReporter writes only the summary object as JSON, leaving out all results.
+type summaryReporter struct {
+	enc *json.Encoder
+}
+
+func (m *summaryReporter) Result(r Result) {
+}
+
+func (m *summaryReporter) Flush() {
+}
+
+func (m *summaryReporter) Close(s *Summary) error {
+	return m.enc.Encode(s)
+}
+
+// calibrations returns calibrations of a run, never nil so that JSON gets an empty array.
+func calibrations(s *Summary) []calibration {
+	if s.Calibrations == nil {
+		return []calibration{}
+	}
+	return s.Calibrations
+}
+
+// A csvReporter streams results as CSV rows.
+type csvReporter struct {
+	w *csv.Writer
+}
+
+var csvHeader = []string{"path", "kind", "estimate", "limit", "subdirs", "sampled", "counted", "device", "device_label", "ratio", "inode_percent", "skip_reason", "fstype", "severity"}
+
+func newCSVReporter(w io.Writer) *csvReporter {
+	c := &csvReporter{w: csv.NewWriter(w)}
+	_ = c.w.Write(csvHeader)
+	return c
+}
+
+func (c *csvReporter) Result(r Result) {
+	_ = c.w.Write([]string{
+		r.Path,
+		r.Kind,
+		strconv.FormatInt(r.Estimate, 10),
+		strconv.FormatInt(r.Limit, 10),
+		strconv.Itoa(r.Subdirs),
+		strconv.Itoa(r.Sampled),
+		strconv.FormatBool(r.Counted),
+		strconv.FormatUint(r.Device, 10),
+		r.Label,
+		strconv.FormatFloat(r.Ratio, 'f', -1, 64),
+		strconv.FormatFloat(r.InodePercent, 'f', -1, 64),
+		r.SkipReason,
+		r.FSType,
+		r.Severity,
+	})
+}
+
+func (c *csvReporter) Flush() {
+	c.w.Flush()
+}
+
+func (c *csvReporter) Close(s *Summary) error {
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,219 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestJSONReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := newReporter(outputJSON, &buf)
+	r.Result(Result{Path: "/a", Kind: resultLarge, Estimate: 100})
+	r.Flush()
+	cal := calibration{Path: "/a", Device: 2050, FSType: "ext4", Ratio: 32, TestFileCount: 100, EmptySize: 4096,
+		FullSize: 7296, Duration: time.Second * 2}
+	s := &Summary{Flagged: 1, Calibrations: []calibration{cal}, CPU: time.Millisecond * 1500, PeakMemory: 1 << 20}
+	if err := r.Close(s); err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		Results      []Result                 `json:"results"`
+		Calibrations []map[string]interface{} `json:"calibrations"`
+		Summary      map[string]interface{}   `json:"summary"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("json reporter produced invalid JSON %q: %v", buf.String(), err)
+	}
+	if len(got.Results) != 1 || got.Results[0].Path != "/a" || got.Summary["flagged"] != float64(1) {
+		t.Errorf("json reporter output = %+v; want a single /a result and one flagged", got)
+	}
+	if _, ok := got.Summary["tool_version"]; !ok {
+		t.Errorf("json reporter summary %v is missing tool_version", got.Summary)
+	}
+	if got.Summary["cpu_seconds"] != 1.5 || got.Summary["peak_memory_bytes"] != float64(1<<20) {
+		t.Errorf("json reporter summary %v is missing resource usage", got.Summary)
+	}
+	if len(got.Calibrations) != 1 || got.Calibrations[0]["fstype"] != "ext4" ||
+		got.Calibrations[0]["full_size"] != float64(7296) || got.Calibrations[0]["duration_seconds"] != float64(2) {
+		t.Errorf("json reporter calibrations = %v; want a single ext4 calibration", got.Calibrations)
+	}
+}
+
+func TestJSONReporterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	r := newReporter(outputJSON, &buf)
+	if err := r.Close(&Summary{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), `{"results":[],`) {
+		t.Errorf("json reporter without results = %q; want an empty results array", buf.String())
+	}
+}
+
+func TestJSONReporterPretty(t *testing.T) {
+	*jsonPrettyFlag = true
+	defer func() { *jsonPrettyFlag = false }()
+
+	var buf bytes.Buffer
+	r := newReporter(outputJSON, &buf)
+	if err := r.Close(&Summary{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "{\n  \"results\": [],\n  \"calibrations\": [],\n  \"summary\": {\n    \"roots\": 0,") {
+		t.Errorf("pretty json reporter output = %q; want two space indentation", buf.String())
+	}
+}
+
+func TestNDJSONReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := newReporter(outputNDJSON, &buf)
+	r.Result(Result{Path: "/a", Kind: resultLarge, Estimate: 100})
+	r.Result(Result{Path: "/b", Kind: resultScanned, Estimate: 5})
+	if err := r.Close(&Summary{Flagged: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("ndjson reporter output = %q; want 3 lines", buf.String())
+	}
+	var got Result
+	if err := json.Unmarshal([]byte(lines[1]), &got); err != nil || got.Path != "/b" || got.Kind != resultScanned {
+		t.Errorf("ndjson reporter line %q = %+v, %v; want scanned /b result", lines[1], got, err)
+	}
+	if !strings.HasPrefix(lines[2], `{"calibrations":[],"summary":{"roots":0,"flagged":1,`) {
+		t.Errorf("ndjson reporter summary line = %q; want summary object", lines[2])
+	}
+}
+
+func TestCSVReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := newReporter(outputCSV, &buf)
+	r.Result(Result{Path: "/a,b", Kind: resultFanout, Estimate: 100, Subdirs: 20, Sampled: 5, Severity: severityWarning})
+	if err := r.Close(&Summary{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "path,kind,estimate,limit,subdirs,sampled,counted,device,device_label,ratio,inode_percent,skip_reason,fstype,severity\n\"/a,b\",fanout,100,0,20,5,false,0,,0,0,,,warning\n"
+	if buf.String() != want {
+		t.Errorf("csv reporter output = %q; want %q", buf.String(), want)
+	}
+}
+
+func TestAtomicFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "report")
+	if err := ioutil.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Aborted write leaves previous file intact
+	f, err := createAtomic(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("partial")
+	f.Abort()
+	if b, _ := ioutil.ReadFile(path); string(b) != "old" {
+		t.Errorf("after Abort() file contains %q; want old", b)
+	}
+
+	f, err = createAtomic(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("new")
+	if err := f.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(path); string(b) != "new" {
+		t.Errorf("after Commit() file contains %q; want new", b)
+	}
+
+	if names, _ := ioutil.ReadDir(dir); len(names) != 1 {
+		t.Errorf("temporary files left behind: %v entries in directory; want 1", len(names))
+	}
+}
+
+func TestYAMLReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := newReporter(outputYAML, &buf)
+	r.Result(Result{Path: "/a: b", Kind: resultLarge, Estimate: 100, Label: "true"})
+	if err := r.Close(&Summary{Flagged: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		Results []Result               `yaml:"results"`
+		Summary map[string]interface{} `yaml:"summary"`
+	}
+	if err := yaml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("yaml reporter produced invalid YAML %q: %v", buf.String(), err)
+	}
+	if !strings.Contains(buf.String(), "\n    device_label: \"true\"\n") {
+		t.Errorf("yaml reporter output = %q; want string labels quoted", buf.String())
+	}
+	if len(got.Results) != 1 || got.Results[0].Path != "/a: b" || got.Summary["flagged"] != 1 {
+		t.Errorf("yaml reporter output = %+v; want a single /a: b result and one flagged", got)
+	}
+	if _, ok := got.Summary["elapsed_seconds"]; !ok {
+		t.Errorf("yaml reporter summary %v is missing JSON summary fields", got.Summary)
+	}
+}
+
+func TestSummaryReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := &summaryReporter{enc: json.NewEncoder(&buf)}
+	r.Result(Result{Path: "/a", Kind: resultLarge, Estimate: 100})
+	r.Flush()
+	s := &Summary{Flagged: 1, Largest: 100, LargestPath: "/a", Parameters: &runParameters{Threshold: 50}}
+	if err := r.Close(s); err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("summary reporter produced invalid JSON %q: %v", buf.String(), err)
+	}
+	if _, ok := got["results"]; ok || got["flagged"] != float64(1) || got["largest_path"] != "/a" {
+		t.Errorf("summary reporter output = %v; want only the summary object", got)
+	}
+	if p, ok := got["parameters"].(map[string]interface{}); !ok || p["threshold"] != float64(50) {
+		t.Errorf("summary reporter parameters = %v; want threshold of 50", got["parameters"])
+	}
+}
@@ -0,0 +1,253 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Result kinds
+const (
+	resultLarge   = "large"
+	resultSuspect = "suspect"
+	resultFanout  = "fanout"
+	resultScanned = "scanned"
+	resultSkipped = "skipped"
+)
+
+// Skip reasons
+const (
+	skipMountPoint   = "mount_point"
+	skipNoRatio      = "no_ratio"
+	skipVanished     = "vanished"
+	skipPermission   = "permission_denied"
+	skipTimeBudget   = "time_budget"
+	skipError        = "error"
+	skipDeviceFilter = "device_filter"
+	skipBindMount    = "bind_mount"
+)
+
+// A Result is a single offending directory found while walking.
+type Result struct {
+	Path         string            `json:"path"`
+	Kind         string            `json:"kind"`
+	Estimate     int64             `json:"estimate"`
+	Limit        int64             `json:"limit,omitempty"`
+	Subdirs      int               `json:"subdirs,omitempty"`
+	Sampled      int               `json:"sampled,omitempty"`
+	Counted      bool              `json:"counted,omitempty"`
+	Estimated    int64             `json:"estimated,omitempty"`
+	Ratio        float64           `json:"ratio,omitempty"`
+	InodePercent float64           `json:"inode_percent,omitempty"`
+	Device       uint64            `json:"device"`
+	Label        string            `json:"device_label"`
+	SkipReason   string            `json:"skip_reason,omitempty"`
+	FSType       string            `json:"fstype,omitempty"`
+	MeasureMS    float64           `json:"measure_ms,omitempty"`
+	DuplicateOf  string            `json:"duplicate_of,omitempty"`
+	Filesystem   *fsContext        `json:"filesystem,omitempty"`
+	Severity     string            `json:"severity,omitempty"`
+	Cleanup      string            `json:"cleanup_command,omitempty"`
+	RAMBacked    bool              `json:"ram_backed,omitempty"`
+	CommonPrefix string            `json:"common_prefix,omitempty"`
+	Tags         map[string]string `json:"tags,omitempty"`
+}
+
+// A Summary holds totals for the whole program run across all roots.
+type Summary struct {
+	Roots        int               `json:"roots"`
+	Flagged      int64             `json:"flagged"`
+	Suspect      int64             `json:"suspect"`
+	Fanout       int64             `json:"fanout"`
+	Vanished     int64             `json:"vanished"`
+	Inaccessible int64             `json:"inaccessible"`
+	Scanned      int64             `json:"scanned"`
+	Incomplete   bool              `json:"incomplete"`
+	Quarantined  int64             `json:"quarantined"`
+	AlertsCapped bool              `json:"alerts_capped"`
+	Unreported   int64             `json:"unreported"`
+	BindMounts   int64             `json:"bind_mount_duplicates"`
+	RAMBacked    int64             `json:"ram_backed_entries"`
+	Severity     string            `json:"severity,omitempty"`
+	Largest      int64             `json:"largest"`
+	LargestPath  string            `json:"largest_path"`
+	CrossChecks  []crossCheck      `json:"cross_checks,omitempty"`
+	Buckets      []sizeBucket      `json:"buckets,omitempty"`
+	Top          []Result          `json:"top_largest,omitempty"`
+	Bottom       []Result          `json:"bottom_smallest_flagged,omitempty"`
+	Partial      []partialDevice   `json:"partial,omitempty"`
+	Shrunk       []shrunkDir       `json:"shrunk,omitempty"`
+	EntryTypes   []entryBreakdown  `json:"entry_types,omitempty"`
+	Leftovers    []string          `json:"leftover_temp_dirs,omitempty"`
+	Drift        []ratioDrift      `json:"ratio_drift,omitempty"`
+	Pressure     []inodePressure   `json:"inode_pressure,omitempty"`
+	Started      time.Time         `json:"started"`
+	PeakMemory   uint64            `json:"peak_memory_bytes"`
+	Parameters   *runParameters    `json:"parameters,omitempty"`
+	ParamsHash   string            `json:"parameters_hash,omitempty"`
+	Tags         map[string]string `json:"tags,omitempty"`
+	Calibrations []calibration     `json:"-"`
+	Elapsed      time.Duration     `json:"-"`
+	CPU          time.Duration     `json:"-"`
+	severities   map[string]int64
+}
+
+// runParameters are scan parameters affecting which directories get flagged and their estimates, recorded in the
+// summary.
+type runParameters struct {
+	Threshold             int64            `json:"threshold"`
+	TestFileCount         int64            `json:"test_file_count"`
+	Accurate              bool             `json:"accurate"`
+	OneFilesystem         bool             `json:"one_filesystem"`
+	CountKind             string           `json:"count_kind"`
+	CritThreshold         int64            `json:"crit_threshold,omitempty"`
+	FSTypeThresholds      map[string]int64 `json:"fstype_thresholds,omitempty"`
+	TmpfsThreshold        int64            `json:"tmpfs_threshold,omitempty"`
+	InodePercentThreshold float64          `json:"inode_percent_threshold,omitempty"`
+	FanoutThreshold       int64            `json:"fanout_threshold"`
+	SampleSubdirs         int64            `json:"sample_subdirs,omitempty"`
+	MaxEstimate           int64            `json:"max_file_count_estimate,omitempty"`
+	MinEstimateFilter     int64            `json:"min_estimate,omitempty"`
+	MaxEstimateFilter     int64            `json:"max_estimate,omitempty"`
+	NewerThan             time.Duration    `json:"newer_than,omitempty"`
+	OlderThan             time.Duration    `json:"older_than,omitempty"`
+	EmptyTolerance        int64            `json:"empty_tolerance,omitempty"`
+	MaxCalibrationFiles   int64            `json:"max_calibration_files,omitempty"`
+	Regression            bool             `json:"calibration_regression,omitempty"`
+	ConvergenceEpsilon    float64          `json:"convergence_epsilon,omitempty"`
+	ConvergenceStep       int64            `json:"convergence_step"`
+	VerifyRatio           bool             `json:"verify_ratio,omitempty"`
+	VerifyTolerance       float64          `json:"verify_ratio_tolerance"`
+	SalvageCalibration    bool             `json:"salvage_calibration,omitempty"`
+	NoTempInTarget        bool             `json:"no_temp_in_target,omitempty"`
+	RootsAreFilesystems   bool             `json:"roots_are_filesystems,omitempty"`
+	Isilon                bool             `json:"isilon,omitempty"`
+	CountFallback         bool             `json:"count_fallback,omitempty"`
+	CountHidden           bool             `json:"count_hidden_in_estimate"`
+	CountBindMounts       bool             `json:"count_bind_mounts,omitempty"`
+	OverlayUnderlying     bool             `json:"overlay_underlying,omitempty"`
+	DescendFlagged        bool             `json:"descend_flagged,omitempty"`
+	ReportEmpty           bool             `json:"report_empty,omitempty"`
+	ReportSkips           bool             `json:"report_skips,omitempty"`
+	MinEntriesForAccurate int64            `json:"min_entries_for_accurate,omitempty"`
+	ConfirmAccurate       bool             `json:"confirm_with_accurate,omitempty"`
+	DeflagShrunk          bool             `json:"deflag_shrunk,omitempty"`
+	MaxAlerts             int64            `json:"max_alerts,omitempty"`
+	StopAtMaxAlerts       bool             `json:"stop_at_max_alerts,omitempty"`
+	MaxRuntimePerFS       time.Duration    `json:"max_runtime_per_fs,omitempty"`
+	OnlyDevices           []string         `json:"only_devices,omitempty"`
+	Subtrees              []string         `json:"subtrees,omitempty"`
+}
+
+// addResult accounts a single result in the summary.
+func (s *Summary) addResult(r Result) {
+	if r.Kind != resultSkipped && r.Kind != resultSuspect {
+		addToBucket(s.Buckets, r.Estimate)
+	}
+	if r.RAMBacked && (r.Kind == resultLarge || r.Kind == resultFanout) {
+		s.RAMBacked += r.Estimate
+	}
+	s.addSeverity(r.Severity, 1)
+
+	switch r.Kind {
+	case resultSuspect:
+		s.Suspect++
+		return
+	case resultFanout:
+		s.Fanout++
+		return
+	case resultScanned, resultSkipped:
+		return
+	}
+
+	s.Flagged++
+	if r.Estimate > s.Largest {
+		s.Largest = r.Estimate
+		s.LargestPath = r.Path
+	}
+	if *topCount > 0 {
+		s.Top = keepRanked(s.Top, r, int(*topCount), false)
+	}
+	if *bottomCount > 0 {
+		s.Bottom = keepRanked(s.Bottom, r, int(*bottomCount), true)
+	}
+}
+
+// keepRanked adds a result to at most n results ranked by estimate, largest first unless smallest ones are kept.
+func keepRanked(ranked []Result, r Result, n int, smallest bool) []Result {
+	ranked = append(ranked, r)
+	sortResults(ranked, sortEstimate, smallest)
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	return ranked
+}
+
+// MarshalJSON encodes summary with elapsed wall-clock and CPU time in seconds and program version.
+func (s Summary) MarshalJSON() ([]byte, error) {
+	type summary Summary
+	return json.Marshal(struct {
+		summary
+		ElapsedSeconds float64 `json:"elapsed_seconds"`
+		CPUSeconds     float64 `json:"cpu_seconds"`
+		ToolVersion    string  `json:"tool_version"`
+	}{summary(s), s.Elapsed.Seconds(), s.CPU.Seconds(), getBuildInfo().Version})
+}
+
+// A resultGroup is a set of large directories sharing a common parent directory.
+type resultGroup struct {
+	Parent   string
+	Estimate int64
+	Children []Result
+}
+
+// groupByParent aggregates results under their parent directories, keeping groups in order of first appearance.
+func groupByParent(results []Result) []resultGroup {
+	var groups []resultGroup
+	index := make(map[string]int)
+
+	for _, r := range results {
+		parent := filepath.Dir(r.Path)
+		i, ok := index[parent]
+		if !ok {
+			i = len(groups)
+			index[parent] = i
+			groups = append(groups, resultGroup{Parent: parent})
+		}
+
+		groups[i].Estimate += r.Estimate
+		groups[i].Children = append(groups[i].Children, r)
+	}
+
+	// Display largest children first within each group
+	for i := range groups {
+		children := groups[i].Children
+		sort.SliceStable(children, func(a, b int) bool {
+			return children[a].Estimate > children[b].Estimate
+		})
+	}
+
+	return groups
+}
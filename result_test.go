@@ -0,0 +1,90 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupByParent(t *testing.T) {
+	results := []Result{
+		{Path: "/var/cache/a", Estimate: 100},
+		{Path: "/home/user", Estimate: 50},
+		{Path: "/var/cache/b", Estimate: 300},
+		{Path: "/var/cache/c", Estimate: 200},
+	}
+
+	want := []resultGroup{
+		{
+			Parent:   "/var/cache",
+			Estimate: 600,
+			Children: []Result{
+				{Path: "/var/cache/b", Estimate: 300},
+				{Path: "/var/cache/c", Estimate: 200},
+				{Path: "/var/cache/a", Estimate: 100},
+			},
+		},
+		{
+			Parent:   "/home",
+			Estimate: 50,
+			Children: []Result{{Path: "/home/user", Estimate: 50}},
+		},
+	}
+
+	if got := groupByParent(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("groupByParent() = %+v; want %+v", got, want)
+	}
+
+	if got := groupByParent(nil); len(got) != 0 {
+		t.Errorf("groupByParent(nil) = %+v; want empty", got)
+	}
+}
+
+func TestSummaryTopBottom(t *testing.T) {
+	defer func() { *topCount, *bottomCount = 0, 0 }()
+	*topCount, *bottomCount = 2, 2
+
+	var s Summary
+	for _, r := range []Result{
+		{Path: "/b", Kind: resultLarge, Estimate: 60000},
+		{Path: "/a", Kind: resultLarge, Estimate: 90000},
+		{Path: "/s", Kind: resultSuspect, Estimate: 1},
+		{Path: "/c", Kind: resultLarge, Estimate: 51000},
+		{Path: "/d", Kind: resultLarge, Estimate: 75000},
+	} {
+		s.addResult(r)
+	}
+
+	paths := func(results []Result) (p []string) {
+		for _, r := range results {
+			p = append(p, r.Path)
+		}
+		return p
+	}
+	if got := paths(s.Top); !reflect.DeepEqual(got, []string{"/a", "/d"}) {
+		t.Errorf("summary top = %v; want [/a /d]", got)
+	}
+	if got := paths(s.Bottom); !reflect.DeepEqual(got, []string{"/c", "/b"}) {
+		t.Errorf("summary bottom = %v; want [/c /b]", got)
+	}
+}
@@ -0,0 +1,58 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// +build !windows
+
+package main
+
+import (
+	"runtime"
+	"syscall"
+	"testing"
+)
+
+func TestLimitWorkers(t *testing.T) {
+	var rl syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rl); err != nil {
+		t.Skip(err)
+	}
+	limit, ok := openFilesLimit(false)
+	if !ok || limit != uint64(rl.Cur) {
+		t.Fatalf("openFilesLimit() = %v, %v; want %v, true", limit, ok, rl.Cur)
+	}
+
+	old := calibrationWorkers
+	defer func() { calibrationWorkers = old }()
+
+	calibrationWorkers = int(limit)
+	limitWorkers()
+	if calibrationWorkers >= int(limit) || calibrationWorkers < 1 {
+		t.Errorf("limitWorkers() with %v workers kept %v; want fewer than the open files limit", limit, calibrationWorkers)
+	}
+
+	calibrationWorkers = runtime.NumCPU()
+	if limit > uint64(reservedOpenFiles+calibrationWorkers) {
+		limitWorkers()
+		if calibrationWorkers != runtime.NumCPU() {
+			t.Errorf("limitWorkers() lowered %v workers to %v under a limit of %v", runtime.NumCPU(), calibrationWorkers, limit)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/dkorunic/findlargedir/fsinfo"
+)
+
+// Root orderings
+const (
+	rootOrderNone   = "none"
+	rootOrderSize   = "size"
+	rootOrderInodes = "inodes"
+)
+
+// dedupRoots drops roots resolving to the same directory as an earlier root through symlinks or redundant
+// separators, so that the same tree doesn't get scanned twice. Roots that can't be resolved are compared as cleaned.
+func dedupRoots(roots []string) []string {
+	seen := make(map[string]string, len(roots))
+	unique := roots[:0:0]
+	for _, root := range roots {
+		root = filepath.Clean(root)
+		resolved, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			resolved = root
+		}
+		resolved = filepath.Clean(resolved)
+		if first, ok := seen[resolved]; ok {
+			log.Printf("Warning: root %q resolves to %q, same as root %q, scanning it only once.", redacted(root), redacted(resolved), redacted(first))
+			continue
+		}
+		seen[resolved] = root
+		unique = append(unique, root)
+	}
+	return unique
+}
+
+// orderRoots orders roots by a cheap pre-check so that likely large ones get scanned first: root directory st_size,
+// or used inode share of the filesystem. Roots that can't be checked go last, keeping their relative order.
+func orderRoots(roots []string, order string) []string {
+	if order == rootOrderNone || len(roots) < 2 {
+		return roots
+	}
+
+	score := make(map[string]float64, len(roots))
+	for _, root := range roots {
+		switch order {
+		case rootOrderSize:
+			if fi, err := os.Lstat(root); err == nil {
+				score[root] = float64(fi.Size())
+			}
+		case rootOrderInodes:
+			if u, err := fsinfo.Statfs(root); err == nil && u.Files > 0 {
+				score[root] = float64(u.UsedFiles()) / float64(u.Files)
+			}
+		}
+	}
+
+	ordered := append([]string(nil), roots...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return score[ordered[i]] > score[ordered[j]]
+	})
+	log.Printf("Scanning roots ordered by %v: %q.", order, redactedAll(ordered))
+	return ordered
+}
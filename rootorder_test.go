@@ -0,0 +1,88 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestOrderRoots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	small, large, missing := filepath.Join(dir, "small"), filepath.Join(dir, "large"), filepath.Join(dir, "missing")
+	for _, d := range []string{small, large} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		if err := ioutil.WriteFile(filepath.Join(large, strconv.Itoa(i)), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	roots := []string{missing, small, large}
+	if got := orderRoots(roots, rootOrderNone); !reflect.DeepEqual(got, roots) {
+		t.Errorf("orderRoots(none) = %q; want %q", got, roots)
+	}
+	want := []string{large, small, missing}
+	if got := orderRoots(roots, rootOrderSize); !reflect.DeepEqual(got, want) {
+		t.Errorf("orderRoots(size) = %q; want %q", got, want)
+	}
+	if !reflect.DeepEqual(roots, []string{missing, small, large}) {
+		t.Errorf("orderRoots() modified its argument to %q", roots)
+	}
+}
+
+func TestDedupRoots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dedup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data, link, other, missing := filepath.Join(dir, "data"), filepath.Join(dir, "link"), filepath.Join(dir, "other"),
+		filepath.Join(dir, "missing")
+	for _, d := range []string{data, other} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(data, link); err != nil {
+		t.Fatal(err)
+	}
+
+	roots := []string{data + "/", other, link, missing, data, missing + "/", other + "//"}
+	want := []string{data, other, missing}
+	if got := dedupRoots(roots); !reflect.DeepEqual(got, want) {
+		t.Errorf("dedupRoots() = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,92 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// calibrationDir returns directory to calibrate filesystem of a directory in. Unless writing inside scan roots is
+// forbidden, that is the directory itself.
+func calibrationDir(path string, dev uint64) (string, bool) {
+	if !*noTempInTargetFlag {
+		return path, true
+	}
+
+	dir, ok := scratchDir(path, dev)
+	if !ok {
+		log.Printf("No writable directory on filesystem of %q outside of scan roots, unable to calibrate.", redacted(path))
+		return "", false
+	}
+	log.Printf("Calibrating filesystem of %q in %q outside of scan roots.", redacted(path), redacted(dir))
+	return dir, true
+}
+
+// scratchDir finds a writable directory on the same device as a directory but outside of all scan roots, going up
+// from its parent to the mount point of its filesystem.
+func scratchDir(path string, dev uint64) (string, bool) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	mountpoint := "/"
+	if info, err := fsCache.Get(dev, path); err == nil {
+		mountpoint = info.Mountpoint
+	}
+
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		if !inScanRoots(dir) && onDevice(dir, dev) && isWritable(dir) {
+			return dir, true
+		}
+		if dir == mountpoint || dir == filepath.Dir(dir) {
+			return "", false
+		}
+	}
+}
+
+// inScanRoots checks if a directory is a scan root or below one.
+func inScanRoots(dir string) bool {
+	for _, root := range scanRoots {
+		if abs, err := filepath.Abs(root); err == nil && isUnder(dir, abs) {
+			return true
+		}
+	}
+	return false
+}
+
+// onDevice checks if a directory resides on a given device.
+func onDevice(dir string, dev uint64) bool {
+	fi, err := os.Lstat(dir)
+	return err == nil && fi.IsDir() && getDev(fi) == dev
+}
+
+// isWritable checks if temporary directories can be created in a directory.
+func isWritable(dir string) bool {
+	tempDir, err := calFS.TempDir(dir, testDirName)
+	if err != nil {
+		return false
+	}
+	calFS.RemoveAll(tempDir)
+	return true
+}
@@ -0,0 +1,57 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScratchDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scratch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(filepath.Join(root, "spool"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Lstat(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved := scanRoots
+	defer func() { scanRoots = saved }()
+	scanRoots = []string{root}
+
+	if got, ok := scratchDir(filepath.Join(root, "spool"), getDev(fi)); !ok || got != dir {
+		t.Errorf("scratchDir() = %q, %v; want %q, true", got, ok, dir)
+	}
+	if !inScanRoots(filepath.Join(root, "spool")) || inScanRoots(dir) {
+		t.Errorf("inScanRoots() does not match only directories within %q", root)
+	}
+}
@@ -0,0 +1,106 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"log"
+)
+
+// Result severities
+const (
+	severityOK       = "ok"
+	severityWarning  = "warning"
+	severityCritical = "critical"
+)
+
+// Exit codes of the worst severity seen with warn-threshold or crit-threshold
+var severityExitCodes = map[string]int{
+	severityWarning:  7,
+	severityCritical: 8,
+}
+
+var severityRank = map[string]int{severityOK: 1, severityWarning: 2, severityCritical: 3}
+
+// severityTiers checks if results are tagged with severities.
+func severityTiers() bool {
+	return *warnThreshold > 0 || *critThreshold > 0
+}
+
+// setSeverityTiers makes warn-threshold the large directory threshold, checking it is below crit-threshold and that
+// threshold wasn't given as well.
+func setSeverityTiers(thresholdSet bool) bool {
+	if *warnThreshold > 0 && thresholdSet {
+		log.Printf("Warning threshold replaces threshold, set only one of them.")
+		return false
+	}
+	if *warnThreshold > 0 {
+		*alertThreshold = *warnThreshold
+	}
+	if *critThreshold > 0 && *critThreshold < *alertThreshold {
+		log.Printf("Critical threshold %v must not be below warning threshold %v.", *critThreshold, *alertThreshold)
+		return false
+	}
+	return true
+}
+
+// severityOf tags a result as critical at or above crit-threshold and as warning if flagged otherwise. Suspect
+// estimates point at a wrong inode ratio rather than at a large directory, so they are not tagged.
+func severityOf(r Result) string {
+	switch {
+	case r.Kind == resultSkipped || r.Kind == resultSuspect:
+		return ""
+	case r.Kind == resultScanned:
+		return severityOK
+	case *critThreshold > 0 && r.Estimate >= *critThreshold:
+		return severityCritical
+	default:
+		return severityWarning
+	}
+}
+
+// addSeverity accounts n results of a severity, negative to take them back, keeping the worst severity still seen.
+func (s *Summary) addSeverity(severity string, n int64) {
+	if severity == "" {
+		return
+	}
+	if s.severities == nil {
+		s.severities = make(map[string]int64)
+	}
+	s.severities[severity] += n
+
+	s.Severity = ""
+	for v, count := range s.severities {
+		if count > 0 && severityRank[v] > severityRank[s.Severity] {
+			s.Severity = v
+		}
+	}
+}
+
+// exitSeverity exits with an exit code of the worst severity seen, if any was flagged.
+func exitSeverity(worst string) {
+	code, ok := severityExitCodes[worst]
+	if !ok {
+		return
+	}
+	log.Printf("Exiting with status %v as the worst severity seen is %v.", code, worst)
+	exit(code)
+}
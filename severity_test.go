@@ -0,0 +1,74 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestSeverityOf(t *testing.T) {
+	saved := *alertThreshold
+	defer func() { *alertThreshold, *warnThreshold, *critThreshold = saved, 0, 0 }()
+
+	*warnThreshold = 1000
+	if setSeverityTiers(true) {
+		t.Errorf("setSeverityTiers() with threshold set as well = true; want false")
+	}
+	*critThreshold = 500
+	if setSeverityTiers(false) {
+		t.Errorf("setSeverityTiers() with critical below warning threshold = true; want false")
+	}
+	*critThreshold = 10000
+	if !setSeverityTiers(false) || *alertThreshold != 1000 {
+		t.Errorf("setSeverityTiers() left threshold at %v; want 1000", *alertThreshold)
+	}
+
+	for _, tt := range []struct {
+		r    Result
+		want string
+	}{
+		{Result{Kind: resultScanned, Estimate: 10}, severityOK},
+		{Result{Kind: resultLarge, Estimate: 5000}, severityWarning},
+		{Result{Kind: resultLarge, Estimate: 10000}, severityCritical},
+		{Result{Kind: resultFanout, Estimate: 20000}, severityCritical},
+		{Result{Kind: resultSkipped}, ""},
+		{Result{Kind: resultSuspect, Estimate: 20000}, ""},
+	} {
+		if got := severityOf(tt.r); got != tt.want {
+			t.Errorf("severityOf(%v %v) = %q; want %q", tt.r.Kind, tt.r.Estimate, got, tt.want)
+		}
+	}
+
+	var s Summary
+	for _, sev := range []string{severityWarning, severityCritical, severityOK} {
+		s.addResult(Result{Kind: resultLarge, Severity: sev})
+	}
+	if s.Severity != severityCritical {
+		t.Errorf("Summary severity = %q; want %q", s.Severity, severityCritical)
+	}
+
+	// Critical directory shrank below threshold by the time it was counted
+	s.addShrunk([]shrunkDir{{Path: "/a", Estimate: 10000, Counted: 10}})
+	if s.Severity != severityWarning {
+		t.Errorf("Summary severity after shrunk directory = %q; want %q", s.Severity, severityWarning)
+	}
+}
@@ -0,0 +1,83 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// +build !windows
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// An interruptingFS interrupts the program once calibration has created a few files.
+type interruptingFS struct {
+	osFS
+	created int32
+}
+
+func (f *interruptingFS) CreateFile(dir, name string, content []byte) (string, error) {
+	if atomic.AddInt32(&f.created, 1) == 100 {
+		_ = syscall.Kill(os.Getpid(), syscall.SIGINT)
+	}
+	if atomic.LoadInt32(&f.created) > 100 {
+		time.Sleep(time.Minute)
+	}
+	return f.osFS.CreateFile(dir, name, content)
+}
+
+func TestCalibrationInterrupted(t *testing.T) {
+	if dir := os.Getenv("FINDLARGEDIR_INTERRUPT_DIR"); dir != "" {
+		calFS = &interruptingFS{}
+		getInodeRatio(dir)
+		os.Exit(0)
+	}
+
+	dir, err := ioutil.TempDir("", "interrupt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestCalibrationInterrupted$")
+	cmd.Env = append(os.Environ(), "FINDLARGEDIR_INTERRUPT_DIR="+dir)
+	out, err := cmd.CombinedOutput()
+	if e, ok := err.(*exec.ExitError); !ok || e.ExitCode() != 1 {
+		t.Fatalf("interrupted calibration exited with %v; want exit status 1\n%s", err, out)
+	}
+	if !strings.Contains(string(out), "Cleaning up temporary directory") {
+		t.Errorf("interrupted calibration output = %q; want cleanup message", out)
+	}
+
+	names, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range names {
+		t.Errorf("interrupted calibration left %v behind", fi.Name())
+	}
+}
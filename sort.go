@@ -0,0 +1,132 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// Sort keys
+const (
+	sortNone     = "none"
+	sortPath     = "path"
+	sortEstimate = "estimate"
+	sortRatio    = "ratio"
+	sortDevice   = "device"
+)
+
+// sortWindowInterval is how often a windowReporter passes on its whole buffer.
+const sortWindowInterval = time.Second * 30
+
+// sortResults orders results by a given key, numeric keys descending and textual keys ascending, ties broken by
+// path. Reverse flips the primary order only.
+func sortResults(results []Result, key string, reverse bool) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+
+		var less, greater bool
+		switch key {
+		case sortEstimate:
+			less, greater = a.Estimate > b.Estimate, a.Estimate < b.Estimate
+		case sortRatio:
+			less, greater = a.Ratio > b.Ratio, a.Ratio < b.Ratio
+		case sortDevice:
+			less, greater = a.Label < b.Label, a.Label > b.Label
+		}
+		if reverse {
+			less, greater = greater, less
+		}
+		if less || greater {
+			return less
+		}
+
+		if key == sortPath && reverse {
+			return a.Path > b.Path
+		}
+		return a.Path < b.Path
+	})
+}
+
+// A sortingReporter buffers results of each root and passes them sorted to another reporter.
+type sortingReporter struct {
+	reporter
+	key     string
+	reverse bool
+	results []Result
+}
+
+func (s *sortingReporter) Result(r Result) {
+	s.results = append(s.results, r)
+}
+
+func (s *sortingReporter) Flush() {
+	sortResults(s.results, s.key, s.reverse)
+	for _, r := range s.results {
+		s.reporter.Result(r)
+	}
+	s.results = nil
+	s.reporter.Flush()
+}
+
+// A windowReporter keeps a bounded buffer of sorted results, passing on the first one whenever the buffer overflows
+// and the whole buffer periodically, so output keeps streaming while being approximately sorted.
+type windowReporter struct {
+	reporter
+	key     string
+	reverse bool
+	size    int
+	results []Result
+	flushed time.Time
+}
+
+func newWindowReporter(r reporter, key string, reverse bool, size int) *windowReporter {
+	return &windowReporter{reporter: r, key: key, reverse: reverse, size: size, flushed: time.Now()}
+}
+
+func (w *windowReporter) Result(r Result) {
+	w.results = append(w.results, r)
+	if time.Since(w.flushed) >= sortWindowInterval {
+		w.emit()
+		return
+	}
+	if len(w.results) > w.size {
+		sortResults(w.results, w.key, w.reverse)
+		w.reporter.Result(w.results[0])
+		w.results = w.results[1:]
+	}
+}
+
+func (w *windowReporter) Flush() {
+	w.emit()
+	w.reporter.Flush()
+}
+
+// emit passes on all buffered results in order.
+func (w *windowReporter) emit() {
+	sortResults(w.results, w.key, w.reverse)
+	for _, r := range w.results {
+		w.reporter.Result(r)
+	}
+	w.results = nil
+	w.flushed = time.Now()
+}
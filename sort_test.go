@@ -0,0 +1,87 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSortResults(t *testing.T) {
+	results := []Result{
+		{Path: "/c", Estimate: 10, Ratio: 32, Label: "/"},
+		{Path: "/a", Estimate: 20, Ratio: 24, Label: "/srv"},
+		{Path: "/b", Estimate: 20, Ratio: 32, Label: "/"},
+	}
+
+	for _, tt := range []struct {
+		key     string
+		reverse bool
+		want    []string
+	}{
+		{sortEstimate, false, []string{"/a", "/b", "/c"}},
+		{sortEstimate, true, []string{"/c", "/a", "/b"}},
+		{sortPath, false, []string{"/a", "/b", "/c"}},
+		{sortPath, true, []string{"/c", "/b", "/a"}},
+		{sortRatio, false, []string{"/b", "/c", "/a"}},
+		{sortDevice, false, []string{"/b", "/c", "/a"}},
+		{sortDevice, true, []string{"/a", "/b", "/c"}},
+	} {
+		sorted := append([]Result(nil), results...)
+		sortResults(sorted, tt.key, tt.reverse)
+
+		var got []string
+		for _, r := range sorted {
+			got = append(got, r.Path)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sortResults(%v, reverse %v) = %v; want %v", tt.key, tt.reverse, got, tt.want)
+		}
+	}
+}
+
+func TestWindowReporter(t *testing.T) {
+	var buf bytes.Buffer
+	w := newWindowReporter(newReporter(outputNDJSON, &buf), sortEstimate, false, 2)
+	for i, e := range []int64{10, 30, 20, 5, 40} {
+		w.Result(Result{Path: string(rune('a' + i)), Estimate: e})
+	}
+	w.Flush()
+
+	var got []int64
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		var r Result
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, r.Estimate)
+	}
+
+	// Largest of each overflowing window goes out first, the rest sorted on flush
+	want := []int64{30, 20, 40, 10, 5}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("window reporter estimates = %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,147 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Output file extensions of output formats
+var outputExtensions = map[string]string{
+	outputHuman:  ".log",
+	outputJSON:   ".json",
+	outputNDJSON: ".ndjson",
+	outputCSV:    ".csv",
+	outputYAML:   ".yaml",
+}
+
+// A splitOutput is a single per-filesystem output file.
+type splitOutput struct {
+	file    *atomicFile
+	r       reporter
+	summary Summary
+	devices map[uint64]bool
+}
+
+// A splitReporter writes results of each filesystem to its own file in a directory, named by device label.
+type splitReporter struct {
+	dir     string
+	format  string
+	outputs map[string]*splitOutput
+	labels  []string
+	err     error
+}
+
+func newSplitReporter(dir, format string) (*splitReporter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &splitReporter{dir: dir, format: format, outputs: make(map[string]*splitOutput)}, nil
+}
+
+func (s *splitReporter) Result(r Result) {
+	o, ok := s.outputs[r.Label]
+	if !ok {
+		path := filepath.Join(s.dir, labelFileName(r.Label)+outputExtensions[s.format])
+		f, err := createAtomic(path)
+		if err != nil {
+			reportError(path, err)
+			if s.err == nil {
+				s.err = err
+			}
+			return
+		}
+
+		o = &splitOutput{file: f, r: newReporter(s.format, f), devices: make(map[uint64]bool)}
+		s.outputs[r.Label] = o
+		s.labels = append(s.labels, r.Label)
+	}
+
+	o.devices[r.Device] = true
+	o.summary.addResult(r)
+	o.r.Result(r)
+}
+
+func (s *splitReporter) Flush() {
+	for _, label := range s.labels {
+		s.outputs[label].r.Flush()
+	}
+}
+
+// Close finalizes each file with a summary of its own filesystem and moves them into place.
+func (s *splitReporter) Close(total *Summary) error {
+	if s.err != nil {
+		s.Abort()
+		return s.err
+	}
+
+	for _, label := range s.labels {
+		o := s.outputs[label]
+		o.summary.Roots, o.summary.Started, o.summary.Elapsed = total.Roots, total.Started, total.Elapsed
+		o.summary.CPU, o.summary.PeakMemory = total.CPU, total.PeakMemory
+		o.summary.Parameters, o.summary.ParamsHash, o.summary.Tags = total.Parameters, total.ParamsHash, total.Tags
+		for _, c := range total.Calibrations {
+			if o.devices[c.Device] {
+				o.summary.Calibrations = append(o.summary.Calibrations, c)
+			}
+		}
+		if err := o.r.Close(&o.summary); err != nil {
+			s.Abort()
+			return err
+		}
+	}
+
+	for _, label := range s.labels {
+		if err := s.outputs[label].file.Commit(); err != nil {
+			s.Abort()
+			return err
+		}
+	}
+	s.outputs, s.labels = nil, nil
+	return nil
+}
+
+// Abort removes all files not yet moved into place.
+func (s *splitReporter) Abort() {
+	for _, label := range s.labels {
+		s.outputs[label].file.Abort()
+	}
+	s.outputs, s.labels = nil, nil
+}
+
+// labelFileName turns a device label (usually a mount point) into a file name.
+func labelFileName(label string) string {
+	name := strings.Trim(label, string(filepath.Separator))
+	if name == "" {
+		return "root"
+	}
+
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', ' ':
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,115 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLabelFileName(t *testing.T) {
+	for label, want := range map[string]string{"/": "root", "/mnt/data": "mnt_data", "scratch": "scratch",
+		"2050": "2050", "/srv/my vol/": "srv_my_vol"} {
+		if got := labelFileName(label); got != want {
+			t.Errorf("labelFileName(%q) = %q; want %q", label, got, want)
+		}
+	}
+}
+
+func TestSplitReporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "split")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "reports")
+	s, err := newSplitReporter(out, outputCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Result(Result{Path: "/big", Kind: resultLarge, Estimate: 100, Device: 1, Label: "/"})
+	s.Result(Result{Path: "/mnt/data/big", Kind: resultLarge, Estimate: 200, Device: 2, Label: "/mnt/data"})
+	s.Result(Result{Path: "/huge", Kind: resultLarge, Estimate: 300, Device: 1, Label: "/"})
+
+	// Nothing is visible until closed
+	if names, _ := filepath.Glob(filepath.Join(out, "*.csv")); len(names) != 0 {
+		t.Errorf("split reporter files before close = %v; want none", names)
+	}
+	s.Flush()
+	if err := s.Close(&Summary{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string][]string{"root.csv": {"/big,", "/huge,"}, "mnt_data.csv": {"/mnt/data/big,"}} {
+		b, err := ioutil.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+		if len(lines) != len(want)+1 {
+			t.Fatalf("%v = %q; want header and %v rows", name, b, len(want))
+		}
+		for i, prefix := range want {
+			if !strings.HasPrefix(lines[i+1], prefix) {
+				t.Errorf("%v row %v = %q; want prefix %q", name, i, lines[i+1], prefix)
+			}
+		}
+	}
+}
+
+func TestSplitReporterSummary(t *testing.T) {
+	dir, err := ioutil.TempDir("", "split")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := newSplitReporter(dir, outputNDJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Result(Result{Path: "/big", Kind: resultLarge, Estimate: 100, Device: 1, Label: "/"})
+	s.Result(Result{Path: "/mnt/data/big", Kind: resultLarge, Estimate: 200, Device: 2, Label: "/mnt/data"})
+	total := &Summary{Parameters: &runParameters{Threshold: 50}, ParamsHash: "abcdef", Tags: map[string]string{"env": "prod"},
+		Calibrations: []calibration{{Path: "/", Device: 1}, {Path: "/mnt/data", Device: 2}}}
+	if err := s.Close(total); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "mnt_data.ndjson"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(b)
+	for _, want := range []string{`"threshold":50`, `"parameters_hash":"abcdef"`, `"env":"prod"`, `"path":"/mnt/data","device":2`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("mnt_data.ndjson = %q; want it to contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, `"device":1`) {
+		t.Errorf("mnt_data.ndjson = %q; want only calibrations of its own device", got)
+	}
+}
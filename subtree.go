@@ -0,0 +1,95 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// subtrees lists directories the walk is limited to, empty when not limited.
+var subtrees []string
+
+// parseSubtrees resolves directories the walk is limited to, each of which must be within one of the roots.
+func parseSubtrees(list, roots []string) error {
+	for _, v := range list {
+		fi, err := os.Stat(v)
+		if err != nil {
+			return fmt.Errorf("invalid subtree %q: %v", v, err)
+		}
+		if !fi.IsDir() {
+			return fmt.Errorf("invalid subtree %q: not a directory", v)
+		}
+
+		abs, err := filepath.Abs(v)
+		if err != nil {
+			return fmt.Errorf("invalid subtree %q: %v", v, err)
+		}
+		if !underRoots(abs, roots) {
+			return fmt.Errorf("invalid subtree %q: not within any of the roots", v)
+		}
+		subtrees = append(subtrees, abs)
+	}
+	return nil
+}
+
+// underRoots checks if an absolute path is a root or below one.
+func underRoots(abs string, roots []string) bool {
+	for _, root := range roots {
+		if r, err := filepath.Abs(root); err == nil && isUnder(abs, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// inSubtree checks if a directory is within one of subtrees the walk is limited to, or if the walk is not limited.
+func inSubtree(path string) bool {
+	if len(subtrees) == 0 {
+		return true
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	for _, st := range subtrees {
+		if isUnder(abs, st) {
+			return true
+		}
+	}
+	return false
+}
+
+// leadsToSubtree checks if a directory holds one of subtrees the walk is limited to.
+func leadsToSubtree(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	for _, st := range subtrees {
+		if isSubpath(abs, st) {
+			return true
+		}
+	}
+	return false
+}
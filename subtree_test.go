@@ -0,0 +1,65 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSubtrees(t *testing.T) {
+	dir, err := ioutil.TempDir("", "subtree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	spool := filepath.Join(dir, "var", "spool")
+	if err := os.MkdirAll(spool, 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { subtrees = nil }()
+
+	if err := parseSubtrees([]string{spool}, []string{filepath.Join(dir, "home")}); err == nil {
+		t.Errorf("parseSubtrees() outside of roots succeeded; want error")
+	}
+	if err := parseSubtrees([]string{filepath.Join(dir, "missing")}, []string{dir}); err == nil {
+		t.Errorf("parseSubtrees() of a missing directory succeeded; want error")
+	}
+	if err := parseSubtrees([]string{spool}, []string{dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string][2]bool{
+		dir:                          {false, true},
+		filepath.Join(dir, "var"):    {false, true},
+		spool:                        {true, false},
+		filepath.Join(spool, "mail"): {true, false},
+		filepath.Join(dir, "home"):   {false, false},
+	} {
+		if in, leads := inSubtree(path), leadsToSubtree(path); in != want[0] || leads != want[1] {
+			t.Errorf("inSubtree(%q), leadsToSubtree() = %v, %v; want %v, %v", path, in, leads, want[0], want[1])
+		}
+	}
+}
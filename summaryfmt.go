@@ -0,0 +1,62 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"text/template"
+)
+
+// summaryTemplate formats a single summary line written once the scan completes, if set.
+var summaryTemplate *template.Template
+
+// parseSummaryTemplate parses and checks a summary line template against an empty summary with parameters.
+func parseSummaryTemplate(text string) (*template.Template, error) {
+	t, err := template.New("summary").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid summary format: %v", err)
+	}
+	if err := t.Execute(ioutil.Discard, &Summary{Parameters: &runParameters{}}); err != nil {
+		return nil, fmt.Errorf("invalid summary format: %v", err)
+	}
+	return t, nil
+}
+
+// writeSummaryLine writes out a summary line formatted with a template, followed by a newline.
+func writeSummaryLine(w io.Writer, t *template.Template, s *Summary) error {
+	if err := t.Execute(w, s); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// summaryWriter returns standard output, unless results are already written there.
+func summaryWriter() io.Writer {
+	if *outputDir == "" && (*outputFile == "-" || *outputFile == "" && *outputFormat != outputHuman) {
+		return os.Stderr
+	}
+	return os.Stdout
+}
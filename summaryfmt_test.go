@@ -0,0 +1,71 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSummaryTemplate(t *testing.T) {
+	tmpl, err := parseSummaryTemplate("{{.Flagged}}/{{.Scanned}} large, largest {{.Largest}} in {{.Elapsed}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	s := &Summary{Scanned: 40, Flagged: 2, Largest: 123456, Elapsed: time.Second * 3}
+	if err := writeSummaryLine(&buf, tmpl, s); err != nil {
+		t.Fatal(err)
+	}
+	if want := "2/40 large, largest 123456 in 3s\n"; buf.String() != want {
+		t.Errorf("writeSummaryLine() = %q; want %q", buf.String(), want)
+	}
+
+	if _, err := parseSummaryTemplate("threshold {{.Parameters.Threshold}}"); err != nil {
+		t.Errorf("parseSummaryTemplate() with parameters = %v; want no error", err)
+	}
+	for _, text := range []string{"{{.Flagged", "{{.NoSuchField}}"} {
+		if _, err := parseSummaryTemplate(text); err == nil {
+			t.Errorf("parseSummaryTemplate(%q) succeeded; want error", text)
+		}
+	}
+}
+
+func TestSummaryTemplateRedacted(t *testing.T) {
+	*redactMode = redactMask
+	defer func() { *redactMode = redactNone }()
+
+	tmpl, err := parseSummaryTemplate("{{.LargestPath}} {{.Flagged}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	s := &Summary{Flagged: 1, LargestPath: "/srv/mail/user"}
+	if err := writeSummaryLine(&buf, tmpl, redactedSummary(s)); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/srv/*/* 1\n"; buf.String() != want || s.LargestPath != "/srv/mail/user" {
+		t.Errorf("writeSummaryLine() of redacted summary = %q; want %q", buf.String(), want)
+	}
+}
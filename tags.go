@@ -0,0 +1,46 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tags are attached to every result and the summary in machine readable output, nil if none were given.
+var tags map[string]string
+
+// parseTags parses key=value tags, later values of the same key replacing earlier ones.
+func parseTags(list []string) error {
+	for _, v := range list {
+		i := strings.IndexByte(v, '=')
+		if i < 1 || i == len(v)-1 {
+			return fmt.Errorf("invalid tag %q, expected key=value", v)
+		}
+
+		if tags == nil {
+			tags = make(map[string]string, len(list))
+		}
+		tags[v[:i]] = v[i+1:]
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	defer func() { tags = nil }()
+
+	if err := parseTags(nil); err != nil || tags != nil {
+		t.Errorf("parseTags(nil) = %v with tags %v; want no tags", err, tags)
+	}
+	if err := parseTags([]string{"env=prod", "team=storage", "env=staging", "url=http://x/?a=b"}); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"env": "staging", "team": "storage", "url": "http://x/?a=b"}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("parseTags() = %v; want %v", tags, want)
+	}
+	for _, v := range []string{"env", "=prod", "env="} {
+		if err := parseTags([]string{v}); err == nil {
+			t.Errorf("parseTags(%q) succeeded; want error", v)
+		}
+	}
+}
+
+func TestTaggedSkip(t *testing.T) {
+	saved := output
+	defer func() { output, tags, *reportSkipsFlag = saved, nil, false }()
+
+	var buf bytes.Buffer
+	output, tags, *reportSkipsFlag = newReporter(outputNDJSON, &buf), map[string]string{"env": "prod"}, true
+	reportSkip("/gone", skipVanished, nil)
+
+	var r Result
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil || r.Tags["env"] != "prod" {
+		t.Errorf("reportSkip() wrote %q; want result tagged env=prod", buf.String())
+	}
+}
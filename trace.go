@@ -0,0 +1,336 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultTraceTimeout = time.Second * 10
+
+// OTLP span kind for operations internal to the program
+const spanKindInternal = 1
+
+// A span is a single timed operation of a trace.
+type span struct {
+	id     string
+	parent string
+	name   string
+	start  time.Time
+	end    time.Time
+	attrs  []spanAttr
+}
+
+// A spanAttr is a single span attribute, holding a string, integer or floating point value.
+type spanAttr struct {
+	key   string
+	value interface{}
+}
+
+// A tracer collects spans of a single program run to be exported via OTLP/HTTP once the run is done.
+type tracer struct {
+	mu      sync.Mutex
+	traceID string
+	spans   []*span
+	run     *span
+	root    *span
+	once    sync.Once
+}
+
+// tracing holds spans of the run, nil when tracing is disabled.
+var tracing *tracer
+
+// measureSpan is the span of measuring the directory currently walked, recorded only if the directory gets flagged.
+var measureSpan *span
+var measureFlagged bool
+
+// strAttr creates a string span attribute.
+func strAttr(key, value string) spanAttr {
+	return spanAttr{key: key, value: value}
+}
+
+// intAttr creates an integer span attribute.
+func intAttr(key string, value int64) spanAttr {
+	return spanAttr{key: key, value: value}
+}
+
+// floatAttr creates a floating point span attribute.
+func floatAttr(key string, value float64) spanAttr {
+	return spanAttr{key: key, value: value}
+}
+
+// newID returns a random hex encoded id of a given size in bytes.
+func newID(size int) string {
+	b := make([]byte, size)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// startTracing starts the run span if tracing is requested, exporting spans on premature exit too.
+func startTracing() {
+	if *traceURL == "" {
+		return
+	}
+
+	tracing = &tracer{traceID: newID(16)}
+	tracing.run = tracing.start("scan", nil)
+	atExit(finishTracing)
+}
+
+// finishTracing ends the run span and exports all spans, only once.
+func finishTracing() {
+	if tracing == nil {
+		return
+	}
+
+	tracing.once.Do(func() {
+		tracing.run.attrs = append(tracing.run.attrs, intAttr("roots", int64(summary.Roots)),
+			intAttr("flagged", summary.Flagged))
+		tracing.finish(tracing.run)
+
+		if err := tracing.export(*traceURL); err != nil {
+			log.Printf("Unable to export traces: %v", err)
+			return
+		}
+		log.Printf("Exported %v spans to %q.", len(tracing.spans), *traceURL)
+	})
+}
+
+// start begins a span as a child of a given parent, or a top-level span when parent is nil.
+func (t *tracer) start(name string, parent *span, attrs ...spanAttr) *span {
+	s := &span{id: newID(8), name: name, start: time.Now(), attrs: attrs}
+	if parent != nil {
+		s.parent = parent.id
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans = append(t.spans, s)
+	return s
+}
+
+// finish ends a span. Spans still open at export time get ended then.
+func (t *tracer) finish(s *span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s.end = time.Now()
+}
+
+// current returns span of the root being walked, or the run span in between roots.
+func (t *tracer) current() *span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.root != nil {
+		return t.root
+	}
+	return t.run
+}
+
+// startSpan begins a span within the current root, doing nothing when tracing is disabled.
+func startSpan(name string, attrs ...spanAttr) *span {
+	if tracing == nil {
+		return nil
+	}
+	return tracing.start(name, tracing.current(), attrs...)
+}
+
+// endSpan ends a span with additional attributes, doing nothing when tracing is disabled.
+func endSpan(s *span, attrs ...spanAttr) {
+	if tracing == nil || s == nil {
+		return
+	}
+
+	tracing.mu.Lock()
+	s.attrs = append(s.attrs, attrs...)
+	tracing.mu.Unlock()
+	tracing.finish(s)
+}
+
+// startMeasureSpan begins a span of measuring a directory within the current root, without recording it yet.
+func startMeasureSpan(path string) {
+	if tracing == nil {
+		return
+	}
+	measureSpan = &span{id: newID(8), parent: tracing.current().id, name: "flagged directory", start: time.Now(),
+		attrs: []spanAttr{strAttr("path", redacted(path))}}
+}
+
+// flagMeasureSpan marks the directory being measured as flagged, adding attributes of its result.
+func flagMeasureSpan(attrs ...spanAttr) {
+	if measureSpan == nil {
+		return
+	}
+	measureSpan.attrs = append(measureSpan.attrs, attrs...)
+	measureFlagged = true
+}
+
+// endMeasureSpan ends the span of measuring a directory, recording it only if the directory was flagged.
+func endMeasureSpan() {
+	if measureSpan != nil && measureFlagged {
+		measureSpan.end = time.Now()
+		tracing.mu.Lock()
+		tracing.spans = append(tracing.spans, measureSpan)
+		tracing.mu.Unlock()
+	}
+	measureSpan, measureFlagged = nil, false
+}
+
+// startRootSpan begins a span of a single root scan, all spans started in the meantime becoming its children.
+func startRootSpan(rootPath string) {
+	if tracing == nil {
+		return
+	}
+
+	s := tracing.start("scan root", tracing.run, strAttr("path", redacted(rootPath)))
+	tracing.mu.Lock()
+	tracing.root = s
+	tracing.mu.Unlock()
+}
+
+// endRootSpan ends the span of a root scan.
+func endRootSpan() {
+	if tracing == nil {
+		return
+	}
+
+	tracing.mu.Lock()
+	s := tracing.root
+	tracing.root = nil
+	tracing.mu.Unlock()
+	if s != nil {
+		tracing.finish(s)
+	}
+}
+
+// export sends all spans to an OTLP/HTTP collector using JSON encoding.
+func (t *tracer) export(collectorURL string) error {
+	var body bytes.Buffer
+	if err := t.encode(&body); err != nil {
+		return err
+	}
+
+	u := strings.TrimRight(collectorURL, "/") + "/v1/traces"
+	req, err := http.NewRequest(http.MethodPost, u, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: defaultTraceTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response status %q", resp.Status)
+	}
+	return nil
+}
+
+// encode writes all spans as an OTLP ExportTraceServiceRequest in JSON encoding.
+func (t *tracer) encode(w *bytes.Buffer) error {
+	type otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+	type otlpAttr struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	type otlpSpan struct {
+		TraceID      string     `json:"traceId"`
+		SpanID       string     `json:"spanId"`
+		ParentSpanID string     `json:"parentSpanId,omitempty"`
+		Name         string     `json:"name"`
+		Kind         int        `json:"kind"`
+		Start        string     `json:"startTimeUnixNano"`
+		End          string     `json:"endTimeUnixNano"`
+		Attributes   []otlpAttr `json:"attributes,omitempty"`
+	}
+
+	attrs := func(list []spanAttr) []otlpAttr {
+		var res []otlpAttr
+		for _, a := range list {
+			var v otlpValue
+			switch x := a.value.(type) {
+			case string:
+				v.StringValue = &x
+			case int64:
+				// OTLP JSON encodes 64-bit integers as strings
+				n := strconv.FormatInt(x, 10)
+				v.IntValue = &n
+			case float64:
+				v.DoubleValue = &x
+			}
+			res = append(res, otlpAttr{Key: a.key, Value: v})
+		}
+		return res
+	}
+
+	t.mu.Lock()
+	now := time.Now()
+	spans := make([]otlpSpan, 0, len(t.spans))
+	for _, s := range t.spans {
+		end := s.end
+		if end.IsZero() {
+			end = now
+		}
+		spans = append(spans, otlpSpan{
+			TraceID:      t.traceID,
+			SpanID:       s.id,
+			ParentSpanID: s.parent,
+			Name:         s.name,
+			Kind:         spanKindInternal,
+			Start:        strconv.FormatInt(s.start.UnixNano(), 10),
+			End:          strconv.FormatInt(end.UnixNano(), 10),
+			Attributes:   attrs(s.attrs),
+		})
+	}
+	t.mu.Unlock()
+
+	version := getBuildInfo().Version
+	return json.NewEncoder(w).Encode(map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": attrs([]spanAttr{strAttr("service.name", "findlargedir"),
+					strAttr("service.version", version)}),
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]string{"name": "findlargedir", "version": version},
+				"spans": spans,
+			}},
+		}},
+	})
+}
@@ -0,0 +1,118 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTracerExport(t *testing.T) {
+	var gotPath, gotType string
+	var got struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID      string `json:"traceId"`
+					SpanID       string `json:"spanId"`
+					ParentSpanID string `json:"parentSpanId"`
+					Name         string `json:"name"`
+					Attributes   []struct {
+						Key   string `json:"key"`
+						Value struct {
+							StringValue string `json:"stringValue"`
+							IntValue    string `json:"intValue"`
+						} `json:"value"`
+					} `json:"attributes"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotType = r.URL.Path, r.Header.Get("Content-Type")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer ts.Close()
+
+	tr := &tracer{traceID: newID(16)}
+	run := tr.start("scan", nil)
+	flagged := tr.start("flagged directory", run, strAttr("path", "/srv/big"), intAttr("estimate", 123456))
+	tr.finish(flagged)
+	if err := tr.export(ts.URL + "/"); err != nil {
+		t.Fatal(err)
+	}
+
+	if gotPath != "/v1/traces" || gotType != "application/json" {
+		t.Errorf("export() sent %q as %q; want /v1/traces as application/json", gotPath, gotType)
+	}
+	if len(got.ResourceSpans) != 1 || len(got.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("export() sent %+v; want a single resource and scope", got)
+	}
+
+	spans := got.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 || spans[1].ParentSpanID != spans[0].SpanID || spans[1].TraceID != tr.traceID ||
+		len(spans[0].SpanID) != 16 || len(tr.traceID) != 32 {
+		t.Fatalf("export() spans = %+v; want flagged directory span as a child of scan span", spans)
+	}
+	attrs := spans[1].Attributes
+	if len(attrs) != 2 || attrs[0].Value.StringValue != "/srv/big" || attrs[1].Value.IntValue != "123456" {
+		t.Errorf("export() attributes = %+v; want path and estimate", attrs)
+	}
+}
+
+func TestMeasureSpan(t *testing.T) {
+	saved := tracing
+	defer func() { tracing = saved }()
+	tracing = &tracer{traceID: newID(16)}
+	tracing.run = tracing.start("scan", nil)
+
+	startMeasureSpan("/srv/small")
+	endMeasureSpan()
+	startMeasureSpan("/srv/big")
+	flagMeasureSpan(strAttr("kind", resultLarge))
+	endMeasureSpan()
+
+	if len(tracing.spans) != 2 {
+		t.Fatalf("measured spans = %+v; want only the run and the flagged directory", tracing.spans)
+	}
+	s := tracing.spans[1]
+	if s.parent != tracing.run.id || s.end.Before(s.start) || len(s.attrs) != 2 || s.attrs[0].value != "/srv/big" {
+		t.Errorf("flagged directory span = %+v; want a finished child of the run span for /srv/big", s)
+	}
+}
+
+func TestMeasureSpanRedacted(t *testing.T) {
+	saved := tracing
+	defer func() { tracing, *redactMode = saved, redactNone }()
+	tracing = &tracer{traceID: newID(16)}
+	tracing.run = tracing.start("scan", nil)
+	*redactMode = redactMask
+
+	startMeasureSpan("/srv/mail/user")
+	flagMeasureSpan(strAttr("kind", resultLarge))
+	endMeasureSpan()
+	if len(tracing.spans) != 2 || tracing.spans[1].attrs[0].value != "/srv/*/*" {
+		t.Errorf("flagged directory span = %+v; want masked path", tracing.spans)
+	}
+}
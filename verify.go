@@ -0,0 +1,100 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"log"
+	"os"
+)
+
+// An accurateCheck is a flagged directory queued for accurate counting.
+type accurateCheck struct {
+	path     string
+	kind     string
+	estimate int64
+}
+
+// A shrunkDir is a large directory whose accurate count fell below threshold, i.e. as entries got deleted after it
+// was estimated.
+type shrunkDir struct {
+	Path      string `json:"path"`
+	Estimate  int64  `json:"estimate"`
+	Counted   int64  `json:"counted"`
+	Deflagged bool   `json:"deflagged"`
+}
+
+// verifyEntries counts entries of a flagged directory, reporting if it is verified to be large. Large directories
+// counted below threshold are returned as shrunk, to be de-flagged if requested. Entry types are returned as well with
+// entry-type-breakdown.
+func verifyEntries(c accurateCheck) (bool, *shrunkDir, *entryBreakdown, error) {
+	var b *entryBreakdown
+	var types *entryTypes
+	if *entryTypeBreakdownFlag {
+		b = &entryBreakdown{Path: c.path}
+		types = &b.Types
+	}
+
+	count, err := countEntries(c.path, *countKindFlag, *countHiddenFlag, types)
+	if err != nil {
+		return false, nil, nil, err
+	}
+	log.Printf("Correct enumeration: directory %q has exactly %v %v.", redacted(c.path), formatCount(int64(count)), countKindNames[*countKindFlag])
+	if b != nil {
+		log.Printf("Directory %q holds %v.", redacted(c.path), b.Types)
+	}
+
+	fi, err := os.Lstat(c.path)
+	if err != nil {
+		return false, nil, b, err
+	}
+	if int64(count) >= thresholdFor(getDev(fi), c.path) {
+		return true, nil, b, nil
+	}
+	if c.kind != resultLarge {
+		return false, nil, b, nil
+	}
+
+	s := &shrunkDir{Path: c.path, Estimate: c.estimate, Counted: int64(count), Deflagged: *deflagShrunkFlag}
+	if s.Deflagged {
+		log.Printf("Directory %q shrank below threshold during verification (estimated %v, counted %v), no longer flagged.",
+			redacted(c.path), c.estimate, count)
+	} else {
+		log.Printf("Directory %q shrank below threshold during verification (estimated %v, counted %v).", redacted(c.path),
+			c.estimate, count)
+	}
+	return false, s, b, nil
+}
+
+// addShrunk accounts directories found shrunk during verification, de-flagging them if requested.
+func (s *Summary) addShrunk(shrunk []shrunkDir) {
+	for _, d := range shrunk {
+		s.Shrunk = append(s.Shrunk, d)
+		if d.Deflagged {
+			s.Flagged--
+		}
+
+		// Directories no longer large don't count towards the worst severity
+		if severityTiers() {
+			s.addSeverity(severityOf(Result{Kind: resultLarge, Estimate: d.Estimate}), -1)
+		}
+	}
+}
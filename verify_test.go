@@ -0,0 +1,77 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestVerifyEntriesShrunk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shrunk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i := 0; i < 10; i++ {
+		if err := ioutil.WriteFile(filepath.Join(dir, strconv.Itoa(i)), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	saved := *alertThreshold
+	defer func() { *alertThreshold, *deflagShrunkFlag = saved, false }()
+	*alertThreshold = 8
+
+	check := accurateCheck{path: dir, kind: resultLarge, estimate: 10}
+	if ok, s, _, err := verifyEntries(check); !ok || s != nil || err != nil {
+		t.Fatalf("verifyEntries() = %v, %+v, %v; want a verified directory", ok, s, err)
+	}
+
+	// Entries get deleted by another process between estimation and verification
+	for i := 0; i < 5; i++ {
+		if err := os.Remove(filepath.Join(dir, strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	*deflagShrunkFlag = true
+	ok, s, _, err := verifyEntries(check)
+	if ok || err != nil || s == nil || s.Counted != 5 || s.Estimate != 10 || !s.Deflagged {
+		t.Fatalf("verifyEntries() of shrunk directory = %v, %+v, %v; want de-flagged with 5 counted", ok, s, err)
+	}
+
+	sum := Summary{Flagged: 3}
+	sum.addShrunk([]shrunkDir{*s})
+	if sum.Flagged != 2 || len(sum.Shrunk) != 1 {
+		t.Errorf("addShrunk() left %v flagged and %v shrunk; want 2 and 1", sum.Flagged, len(sum.Shrunk))
+	}
+
+	check.kind = resultSuspect
+	if ok, s, _, err := verifyEntries(check); ok || s != nil || err != nil {
+		t.Errorf("verifyEntries() of suspect directory = %v, %+v, %v; want nothing shrunk", ok, s, err)
+	}
+}
@@ -0,0 +1,85 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata, populated with -ldflags "-X main.version=... -X main.commit=... -X main.date=..." at build time.
+var (
+	version = ""
+	commit  = ""
+	date    = ""
+)
+
+const unknownVersion = "unknown"
+
+// buildInfo holds version details for the running binary.
+type buildInfo struct {
+	Version   string
+	Commit    string
+	Date      string
+	GoVersion string
+}
+
+// getBuildInfo returns build metadata from linker flags, falling back to embedded module build information.
+func getBuildInfo() buildInfo {
+	bi := buildInfo{
+		Version:   version,
+		Commit:    commit,
+		Date:      date,
+		GoVersion: runtime.Version(),
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		fillBuildInfo(&bi, info)
+	}
+
+	if bi.Version == "" {
+		bi.Version = unknownVersion
+	}
+	if bi.Commit == "" {
+		bi.Commit = unknownVersion
+	}
+	if bi.Date == "" {
+		bi.Date = unknownVersion
+	}
+
+	return bi
+}
+
+// fillBuildInfo fills in any build metadata not set by linker flags from module build information.
+func fillBuildInfo(bi *buildInfo, info *debug.BuildInfo) {
+	if bi.Version == "" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		bi.Version = info.Main.Version
+	}
+
+	fillBuildSettings(bi, info)
+}
+
+// String returns human readable build metadata.
+func (bi buildInfo) String() string {
+	return fmt.Sprintf("findlargedir %v (commit %v, built %v, %v)", bi.Version, bi.Commit, bi.Date, bi.GoVersion)
+}
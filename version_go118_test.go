@@ -0,0 +1,61 @@
+// @license
+// Copyright (C) 2018  Dinko Korunic
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// +build go1.18
+
+package main
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestFillBuildInfo(t *testing.T) {
+	info := &debug.BuildInfo{
+		GoVersion: "go1.99",
+		Main:      debug.Module{Version: "v1.2.3"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "abcdef"},
+			{Key: "vcs.time", Value: "2020-01-01T00:00:00Z"},
+		},
+	}
+
+	cases := []struct {
+		in   buildInfo
+		want buildInfo
+	}{
+		{
+			in:   buildInfo{},
+			want: buildInfo{Version: "v1.2.3", Commit: "abcdef", Date: "2020-01-01T00:00:00Z", GoVersion: "go1.99"},
+		},
+		{
+			in:   buildInfo{Version: "1.0.0", Commit: "123456", Date: "2019-01-01"},
+			want: buildInfo{Version: "1.0.0", Commit: "123456", Date: "2019-01-01", GoVersion: "go1.99"},
+		},
+	}
+	for _, tc := range cases {
+		bi := tc.in
+		fillBuildInfo(&bi, info)
+		if bi != tc.want {
+			t.Errorf("fillBuildInfo(%+v) = %+v; want %+v", tc.in, bi, tc.want)
+		}
+	}
+}
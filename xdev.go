@@ -30,5 +30,13 @@ import (
 
 // isSameFilesystem compares if two entries have the same root device number st_dev.
 func isSameFilesystem(rootStat, osStat os.FileInfo) bool {
-	return rootStat.Sys().(*syscall.Stat_t).Dev == osStat.Sys().(*syscall.Stat_t).Dev
+	return getDev(rootStat) == getDev(osStat)
+}
+
+// getDev returns device number st_dev of an entry or 0 if unavailable.
+func getDev(fi os.FileInfo) uint64 {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Dev)
+	}
+	return 0
 }
@@ -31,3 +31,8 @@ import (
 func isSameFilesystem(rootStat, osStat os.FileInfo) bool {
 	return true
 }
+
+// getDev always returns 0 on Windows.
+func getDev(fi os.FileInfo) uint64 {
+	return 0
+}